@@ -6,7 +6,6 @@ package cmd
 
 import (
 	"hack/internal/asm"
-	"log"
 	"os"
 	"path"
 	"strings"
@@ -14,29 +13,57 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var (
+	assembleListing  bool
+	assembleOptimize bool
+)
+
 var assembleCommand = &cobra.Command{
-	Use:  "assemble",
-	Args: cobra.ExactArgs(1),
+	Use:   "assemble",
+	Short: "Assemble a .asm file into Hack machine code",
+	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		asmFilePath := args[0]
 		hackFilePath := strings.TrimSuffix(asmFilePath, path.Ext(asmFilePath)) + ".hack"
+		lstFilePath := strings.TrimSuffix(asmFilePath, path.Ext(asmFilePath)) + ".lst"
 
-		if prog, err := parseAsm(asmFilePath); err != nil {
-			log.Fatal(err)
-		} else if err = assemble(hackFilePath, prog); err != nil {
-			log.Fatal(err)
+		prog, src, err := parseAsm(asmFilePath)
+		if err != nil {
+			fatal(err)
+		}
+		if assembleOptimize {
+			if prog, err = prog.Optimize(asm.Rules...); err != nil {
+				fatal(err)
+			}
+			// The listing shows the optimized program rather than the source.
+			if src, err = prog.FormatSource(asmFilePath); err != nil {
+				fatal(err)
+			}
+		}
+		if err = assemble(hackFilePath, prog); err != nil {
+			fatal(err)
+		}
+		if assembleListing {
+			if err = listing(lstFilePath, prog, src); err != nil {
+				fatal(err)
+			}
 		}
 	},
 }
 
-func parseAsm(filePath string) (prog asm.Program, err error) {
+func init() {
+	assembleCommand.Flags().BoolVar(&assembleListing, "listing", false, "also write a .lst listing file, of the optimized program with -O")
+	assembleCommand.Flags().BoolVarP(&assembleOptimize, "optimize", "O", false, "apply peephole optimizations before assembling")
+}
+
+func parseAsm(filePath string) (prog asm.Program, src *asm.Source, err error) {
 	var file *os.File
 	if file, err = os.Open(filePath); err != nil {
 		return
 	}
 	defer file.Close()
 
-	if prog, err = asm.Parse(file); err != nil {
+	if prog, src, err = asm.ParseSource(filePath, file, asm.AllErrors); err != nil {
 		return
 	}
 
@@ -60,3 +87,17 @@ func assemble(filePath string, prog asm.Program) (err error) {
 
 	return
 }
+
+func listing(filePath string, prog asm.Program, src *asm.Source) (err error) {
+	var file *os.File
+	if file, err = os.Create(filePath); err != nil {
+		return
+	}
+	defer file.Close()
+
+	if err = prog.Listing(file, src); err != nil {
+		return
+	}
+
+	return
+}
@@ -0,0 +1,201 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"hack/internal/asm"
+	"hack/internal/cpu"
+	"hack/internal/vm"
+	"hack/internal/vme"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	buildOutput  string
+	buildOSDir   string
+	buildKeepVM  bool
+	buildKeepAsm bool
+)
+
+var buildCommand = &cobra.Command{
+	Use:   "build",
+	Short: "Build a directory of .jack and .vm files into a .hack program",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := build(args[0]); err != nil {
+			fatal(err)
+		}
+	},
+}
+
+func init() {
+	buildCommand.Flags().StringVarP(&buildOutput, "output", "o", "", "write the machine code to `file` instead of DIR/DIR.hack")
+	buildCommand.Flags().StringVar(&buildOSDir, "os", "", "link the OS classes the program calls from the .vm or .jack files of `dir`")
+	buildCommand.Flags().BoolVar(&buildKeepVM, "keep-vm", false, "keep the .vm files compiled from the .jack files")
+	buildCommand.Flags().BoolVar(&buildKeepAsm, "keep-asm", false, "keep the translated DIR/DIR.asm file")
+}
+
+// program is a set of VM classes, in the order they are translated.
+type program struct {
+	names []string
+	progs []vm.Program
+}
+
+func (p *program) add(name string, prog vm.Program) {
+	p.names = append(p.names, name)
+	p.progs = append(p.progs, prog)
+}
+
+// build compiles the .jack files of dir, links them with the .vm files of
+// dir and the OS, and writes the resulting machine code.
+func build(dir string) (err error) {
+	var absDir string
+	if absDir, err = filepath.Abs(dir); err != nil {
+		return
+	}
+	base := filepath.Join(dir, filepath.Base(absDir))
+
+	p := &program{}
+
+	var jackFilePaths, vmFilePaths []string
+	if jackFilePaths, err = filepath.Glob(filepath.Join(dir, "*.jack")); err != nil {
+		return
+	}
+	if vmFilePaths, err = filepath.Glob(filepath.Join(dir, "*.vm")); err != nil {
+		return
+	}
+	if len(jackFilePaths)+len(vmFilePaths) == 0 {
+		return &fs.PathError{Op: "build", Path: dir, Err: fs.ErrNotExist}
+	}
+
+	for _, jackFilePath := range jackFilePaths {
+		var prog vm.Program
+		if prog, err = compileJack(jackFilePath); err != nil {
+			return
+		}
+		if buildKeepVM {
+			if err = writeVM(outputPath(jackFilePath, "", ".vm"), prog); err != nil {
+				return
+			}
+		}
+		p.add(className(jackFilePath), prog)
+	}
+
+	// Prebuilt classes, such as a copy of the OS, complete the compiled ones.
+	for _, vmFilePath := range vmFilePaths {
+		if slices.Contains(p.names, className(vmFilePath)) {
+			continue
+		}
+		var prog vm.Program
+		if prog, _, err = parseVM(vmFilePath); err != nil {
+			return
+		}
+		p.add(className(vmFilePath), prog)
+	}
+
+	if buildOSDir != "" {
+		if err = p.linkOS(buildOSDir); err != nil {
+			return
+		}
+	} else if err = p.checkOS(); err != nil {
+		return
+	}
+	if err = vm.Link([]string{vm.BootstrapFunction}, p.progs...); err != nil {
+		return
+	}
+
+	var instrs asm.Program
+	if instrs, err = translatePrograms(p.names, p.progs, nil, true, nil); err != nil {
+		return
+	}
+	if buildKeepAsm {
+		if err = writeAsm(base+".asm", instrs); err != nil {
+			return
+		}
+	}
+
+	if size := len(instrs) - countLabels(instrs); size > cpu.ROMSize {
+		return cpu.ErrROMOverflow
+	}
+
+	hackFilePath := buildOutput
+	if hackFilePath == "" {
+		hackFilePath = base + ".hack"
+	}
+	return assemble(hackFilePath, instrs)
+}
+
+// linkOS adds the classes of osDir defining functions the program calls,
+// until no more can be resolved.
+func (p *program) linkOS(osDir string) (err error) {
+	for {
+		added := false
+		for _, function := range vm.Undefined([]string{vm.BootstrapFunction}, p.progs...) {
+			class, _, _ := strings.Cut(function, ".")
+			if slices.Contains(p.names, class) {
+				continue
+			}
+
+			var prog vm.Program
+			var ok bool
+			if prog, ok, err = loadClass(osDir, class); err != nil {
+				return
+			} else if ok {
+				p.add(class, prog)
+				added = true
+			}
+		}
+		if !added {
+			return
+		}
+	}
+}
+
+// checkOS reports the first OS function the program calls without defining
+// it, as the OS is not linked in without --os.
+func (p *program) checkOS() error {
+	for _, function := range vm.Undefined([]string{vm.BootstrapFunction}, p.progs...) {
+		if _, ok := vme.Builtins[function]; ok || function == vm.BootstrapFunction {
+			return ErrOSMissing{function: function}
+		}
+	}
+	return nil
+}
+
+// loadClass loads class from dir, preferring its .vm file to its .jack file.
+func loadClass(dir, class string) (prog vm.Program, ok bool, err error) {
+	filePath := filepath.Join(dir, class+".vm")
+	if _, err = os.Stat(filePath); err == nil {
+		prog, _, err = parseVM(filePath)
+		return prog, err == nil, err
+	}
+
+	filePath = filepath.Join(dir, class+".jack")
+	if _, err = os.Stat(filePath); err == nil {
+		prog, err = compileJack(filePath)
+		return prog, err == nil, err
+	}
+
+	return nil, false, nil
+}
+
+func className(filePath string) string {
+	return strings.TrimSuffix(filepath.Base(filePath), filepath.Ext(filePath))
+}
+
+func countLabels(instrs asm.Program) (count int) {
+	for _, instr := range instrs {
+		if _, ok := instr.(*asm.LabelInstruction); ok {
+			count += 1
+		}
+	}
+	return
+}
@@ -0,0 +1,81 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"fmt"
+	"hack/internal/jack"
+	"hack/internal/vm"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	compileOutputDir string
+	compileOptimize  bool
+)
+
+var compileCommand = &cobra.Command{
+	Use:   "compile",
+	Short: "Compile .jack files into VM code",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		jackFilePaths, err := jackInputs(args[0])
+		if err != nil {
+			fatal(err)
+		}
+
+		for _, jackFilePath := range jackFilePaths {
+			var prog vm.Program
+			if prog, err = compileJack(jackFilePath); err != nil {
+				fatal(err)
+			}
+			if compileOptimize {
+				prog = optimizeVM(jackFilePath, prog)
+			}
+			if err = writeVM(outputPath(jackFilePath, compileOutputDir, ".vm"), prog); err != nil {
+				fatal(err)
+			}
+		}
+	},
+}
+
+func init() {
+	compileCommand.Flags().StringVarP(&compileOutputDir, "output-dir", "d", "", "write the .vm files to `dir` instead of next to the sources")
+	compileCommand.Flags().BoolVarP(&compileOptimize, "optimize", "O", false, "optimize the generated VM code, reporting the statements removed")
+}
+
+func compileJack(jackFilePath string) (prog vm.Program, err error) {
+	var file *os.File
+	if file, err = os.Open(jackFilePath); err != nil {
+		return
+	}
+	defer file.Close()
+
+	return jack.CompileFile(jackFilePath, file)
+}
+
+// optimizeVM optimizes prog, read from filePath, and reports how many
+// statements were removed.
+func optimizeVM(filePath string, prog vm.Program) vm.Program {
+	optimized, stats := prog.Optimize(vm.Rules...)
+	fmt.Fprintf(os.Stderr, "%s: %s\n", filePath, stats)
+	return optimized
+}
+
+func writeVM(filePath string, prog vm.Program) (err error) {
+	var file *os.File
+	if file, err = os.Create(filePath); err != nil {
+		return
+	}
+	defer file.Close()
+
+	if err = prog.Format(file); err != nil {
+		return
+	}
+	_, err = file.Write([]byte{'\n'})
+	return
+}
@@ -0,0 +1,33 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"hack/internal/debug"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var debugCommand = &cobra.Command{
+	Use:   "debug",
+	Short: "Debug a .asm program on the CPU emulator",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		prog, src, err := parseAsm(args[0])
+		if err != nil {
+			fatal(err)
+		}
+
+		dbg, err := debug.New(prog, src)
+		if err != nil {
+			fatal(err)
+		}
+		dbg.Out = cmd.OutOrStdout()
+		if err = dbg.Run(os.Stdin); err != nil {
+			fatal(err)
+		}
+	},
+}
@@ -0,0 +1,69 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"hack/internal/asm"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	disassembleLabels bool
+	disassembleOutput string
+)
+
+var disassembleCommand = &cobra.Command{
+	Use:   "disassemble",
+	Short: "Disassemble a .hack file into assembly",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		prog, err := disassemble(args[0])
+		if err != nil {
+			fatal(err)
+		}
+		if disassembleLabels {
+			prog = prog.SynthesizeLabels()
+		}
+
+		w := io.Writer(os.Stdout)
+		if disassembleOutput != "" {
+			var file *os.File
+			if file, err = os.Create(disassembleOutput); err != nil {
+				fatal(err)
+			}
+			defer file.Close()
+			w = file
+		}
+
+		if err = prog.Format(w); err != nil {
+			fatal(err)
+		}
+		if _, err = w.Write([]byte{'\n'}); err != nil {
+			fatal(err)
+		}
+	},
+}
+
+func init() {
+	disassembleCommand.Flags().BoolVar(&disassembleLabels, "labels", false, "synthesize labels for jump targets")
+	disassembleCommand.Flags().StringVarP(&disassembleOutput, "output", "o", "", "write to `file` instead of stdout")
+}
+
+func disassemble(filePath string) (prog asm.Program, err error) {
+	var file *os.File
+	if file, err = os.Open(filePath); err != nil {
+		return
+	}
+	defer file.Close()
+
+	if prog, err = asm.DisassembleFile(filePath, file); err != nil {
+		return
+	}
+
+	return
+}
@@ -0,0 +1,15 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+type (
+	ErrOSMissing struct {
+		function string
+	}
+)
+
+func (err ErrOSMissing) Error() string {
+	return "undefined OS function " + err.function + ": link the OS with --os dir"
+}
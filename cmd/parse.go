@@ -0,0 +1,67 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"hack/internal/jack"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var parseOutputDir string
+
+var parseCommand = &cobra.Command{
+	Use:   "parse",
+	Short: "Parse .jack files into XML syntax trees",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		jackFilePaths, err := jackInputs(args[0])
+		if err != nil {
+			fatal(err)
+		}
+
+		for _, jackFilePath := range jackFilePaths {
+			if err = parse(jackFilePath); err != nil {
+				fatal(err)
+			}
+		}
+	},
+}
+
+func init() {
+	parseCommand.Flags().StringVarP(&parseOutputDir, "output-dir", "d", "", "write Xxx.xml files to `dir` instead of Xxx.out.xml files next to the sources")
+}
+
+func parseJack(jackFilePath string) (class *jack.Class, err error) {
+	var file *os.File
+	if file, err = os.Open(jackFilePath); err != nil {
+		return
+	}
+	defer file.Close()
+
+	return jack.ParseFile(jackFilePath, file)
+}
+
+func parse(jackFilePath string) (err error) {
+	var class *jack.Class
+	if class, err = parseJack(jackFilePath); err != nil {
+		return
+	}
+
+	// Next to the sources, Xxx.xml is the reference output to compare with.
+	suffix := ".out.xml"
+	if parseOutputDir != "" {
+		suffix = ".xml"
+	}
+
+	var out *os.File
+	if out, err = os.Create(outputPath(jackFilePath, parseOutputDir, suffix)); err != nil {
+		return
+	}
+	defer out.Close()
+
+	return class.FormatXML(out)
+}
@@ -5,7 +5,11 @@
 package cmd
 
 import (
+	"errors"
+	"fmt"
+	"hack/internal/diag"
 	"log"
+	"os"
 
 	"github.com/spf13/cobra"
 )
@@ -14,9 +18,20 @@ var rootCmd = &cobra.Command{
 	Use: "hack",
 }
 
+var maxErrors int
+
 func init() {
+	rootCmd.PersistentFlags().IntVar(&maxErrors, "max-errors", 10, "maximum number of diagnostics to report, 0 for no limit")
+
 	rootCmd.AddCommand(assembleCommand)
 	rootCmd.AddCommand(translateCommand)
+	rootCmd.AddCommand(disassembleCommand)
+	rootCmd.AddCommand(testCommand)
+	rootCmd.AddCommand(tokenizeCommand)
+	rootCmd.AddCommand(parseCommand)
+	rootCmd.AddCommand(compileCommand)
+	rootCmd.AddCommand(buildCommand)
+	rootCmd.AddCommand(debugCommand)
 }
 
 func Execute() {
@@ -24,3 +39,32 @@ func Execute() {
 		log.Fatal(err)
 	}
 }
+
+// fatal reports err and exits.
+func fatal(err error) {
+	report(err)
+	os.Exit(1)
+}
+
+// report prints err to stderr. Diagnostics are printed compiler-style, with
+// their source line, up to maxErrors of them.
+func report(err error) {
+	var l diag.List
+	var d diag.Diagnostic
+	switch {
+	case errors.As(err, &l):
+	case errors.As(err, &d):
+		l = diag.List{d}
+	default:
+		log.Print(err)
+		return
+	}
+
+	for idx, d := range l {
+		if maxErrors > 0 && idx == maxErrors {
+			fmt.Fprintf(os.Stderr, "too many errors (%d more)\n", len(l)-idx)
+			break
+		}
+		d.Format(os.Stderr)
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"fmt"
+	"hack/internal/tst"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	testHDLPath  string
+	testForceHDL []string
+)
+
+var testCommand = &cobra.Command{
+	Use:   "test",
+	Short: "Run .tst test scripts",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var hdlPath []string
+		if testHDLPath != "" {
+			hdlPath = filepath.SplitList(testHDLPath)
+		}
+
+		failed := false
+		for _, tstFilePath := range args {
+			r := &tst.Runner{Echo: os.Stdout, HDLPath: hdlPath, ForceHDL: testForceHDL}
+			if _, err := r.RunFile(tstFilePath); err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "FAIL %s\n", tstFilePath)
+				report(err)
+				failed = true
+			} else {
+				fmt.Fprintf(cmd.OutOrStdout(), "ok   %s\n", tstFilePath)
+			}
+		}
+		if failed {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	testCommand.Flags().StringVar(&testHDLPath, "hdl-path", "", "search the colon-separated `dirs` for the parts of a chip not next to it")
+	testCommand.Flags().StringSliceVar(&testForceHDL, "force-hdl", nil, "load the comma-separated `chips` from their .hdl files instead of the builtin chips")
+}
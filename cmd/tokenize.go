@@ -0,0 +1,96 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"hack/internal/jack"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var tokenizeOutputDir string
+
+var tokenizeCommand = &cobra.Command{
+	Use:   "tokenize",
+	Short: "Tokenize .jack files into XML token lists",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		jackFilePaths, err := jackInputs(args[0])
+		if err != nil {
+			fatal(err)
+		}
+
+		for _, jackFilePath := range jackFilePaths {
+			if err = tokenize(jackFilePath); err != nil {
+				fatal(err)
+			}
+		}
+	},
+}
+
+func init() {
+	tokenizeCommand.Flags().StringVarP(&tokenizeOutputDir, "output-dir", "d", "", "write XxxT.xml files to `dir` instead of XxxT.out.xml files next to the sources")
+}
+
+// jackInputs resolves the .jack files of a file or a directory path.
+func jackInputs(inputPath string) (jackFilePaths []string, err error) {
+	var info os.FileInfo
+	if info, err = os.Stat(inputPath); err != nil {
+		return
+	}
+
+	if !info.IsDir() {
+		return []string{inputPath}, nil
+	}
+
+	if jackFilePaths, err = filepath.Glob(filepath.Join(inputPath, "*.jack")); err != nil {
+		return
+	}
+	if len(jackFilePaths) == 0 {
+		err = &fs.PathError{Op: "read", Path: inputPath, Err: fs.ErrNotExist}
+	}
+	return
+}
+
+// outputPath returns the path of the file derived from inputPath by replacing
+// its extension with suffix, inside dir if set.
+func outputPath(inputPath, dir, suffix string) string {
+	outPath := strings.TrimSuffix(inputPath, filepath.Ext(inputPath)) + suffix
+	if dir != "" {
+		outPath = filepath.Join(dir, filepath.Base(outPath))
+	}
+	return outPath
+}
+
+func tokenize(jackFilePath string) (err error) {
+	var file *os.File
+	if file, err = os.Open(jackFilePath); err != nil {
+		return
+	}
+	defer file.Close()
+
+	var tokens []jack.Token
+	if tokens, err = jack.TokenizeFile(jackFilePath, file); err != nil {
+		return
+	}
+
+	// Next to the sources, XxxT.xml is the reference output to compare with.
+	suffix := "T.out.xml"
+	if tokenizeOutputDir != "" {
+		suffix = "T.xml"
+	}
+
+	var out *os.File
+	if out, err = os.Create(outputPath(jackFilePath, tokenizeOutputDir, suffix)); err != nil {
+		return
+	}
+	defer out.Close()
+
+	return jack.FormatTokens(out, tokens)
+}
@@ -5,47 +5,178 @@
 package cmd
 
 import (
+	"errors"
+	"hack/internal/asm"
+	"hack/internal/diag"
 	"hack/internal/vm"
-	"log"
+	"io/fs"
 	"os"
 	"path"
+	"path/filepath"
 	"strings"
 
 	"github.com/spf13/cobra"
 )
 
+var (
+	translateSourceMap  bool
+	translateOptimize   bool
+	translateOptimizeVM bool
+)
+
 var translateCommand = &cobra.Command{
-	Use:  "translate",
-	Args: cobra.ExactArgs(1),
+	Use:   "translate",
+	Short: "Translate VM code into assembly",
+	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		vmFilePath := args[0]
-		asmFilePath := strings.TrimSuffix(vmFilePath, path.Ext(vmFilePath)) + ".asm"
+		asmFilePath, vmFilePaths, bootstrap, err := translateInputs(args[0])
+		if err != nil {
+			fatal(err)
+		}
 
-		if prog, err := parseVM(vmFilePath); err != nil {
-			log.Fatal(err)
-		} else if err = translate(asmFilePath, prog); err != nil {
-			log.Fatal(err)
+		var sourceMap *vm.SourceMap
+		if translateSourceMap {
+			sourceMap = &vm.SourceMap{}
+		}
+
+		instrs, err := translateFiles(vmFilePaths, bootstrap, translateOptimizeVM, sourceMap)
+		if err != nil {
+			fatal(err)
+		}
+		if translateOptimize {
+			var optimized asm.Program
+			if optimized, err = instrs.Optimize(asm.Rules...); err != nil {
+				fatal(err)
+			}
+			if sourceMap != nil {
+				sourceMap.Remap(instrs, optimized)
+			}
+			instrs = optimized
+		}
+		if err = writeAsm(asmFilePath, instrs); err != nil {
+			fatal(err)
+		}
+
+		if sourceMap != nil {
+			mapFilePath := strings.TrimSuffix(asmFilePath, path.Ext(asmFilePath)) + ".map.json"
+			if err = writeSourceMap(mapFilePath, sourceMap); err != nil {
+				fatal(err)
+			}
 		}
 	},
 }
 
-func parseVM(filePath string) (prog vm.Program, err error) {
+func init() {
+	translateCommand.Flags().BoolVar(&translateSourceMap, "source-map", false, "also write a .map.json file mapping VM statements to assembly and ROM addresses")
+	translateCommand.Flags().BoolVarP(&translateOptimize, "optimize", "O", false, "apply peephole optimizations to the generated assembly")
+	translateCommand.Flags().BoolVar(&translateOptimizeVM, "optimize-vm", false, "optimize the VM code before translating it, reporting the statements removed; source map entries then have no line")
+}
+
+// translateInputs resolves the .vm files to translate from a file or a
+// directory path, along with the output path and whether bootstrap code is
+// required.
+func translateInputs(inputPath string) (asmFilePath string, vmFilePaths []string, bootstrap bool, err error) {
+	var info os.FileInfo
+	if info, err = os.Stat(inputPath); err != nil {
+		return
+	}
+
+	if !info.IsDir() {
+		asmFilePath = strings.TrimSuffix(inputPath, path.Ext(inputPath)) + ".asm"
+		vmFilePaths = []string{inputPath}
+		return
+	}
+
+	var absPath string
+	if absPath, err = filepath.Abs(inputPath); err != nil {
+		return
+	}
+	asmFilePath = filepath.Join(inputPath, filepath.Base(absPath)+".asm")
+	if vmFilePaths, err = filepath.Glob(filepath.Join(inputPath, "*.vm")); err != nil {
+		return
+	}
+	if len(vmFilePaths) == 0 {
+		err = &fs.PathError{Op: "translate", Path: inputPath, Err: fs.ErrNotExist}
+		return
+	}
+	bootstrap = true
+
+	return
+}
+
+// translateFiles translates the .vm files at filePaths into a single
+// program, optimizing the VM code of each first if optimize is set.
+func translateFiles(filePaths []string, bootstrap, optimize bool, sourceMap *vm.SourceMap) (instrs asm.Program, err error) {
+	names := make([]string, len(filePaths))
+	progs := make([]vm.Program, len(filePaths))
+	srcs := make([]*vm.Source, len(filePaths))
+	// Every file is parsed, so that the diagnostics of all of them are
+	// reported at once.
+	var diags diag.List
+	for idx, filePath := range filePaths {
+		if progs[idx], srcs[idx], err = parseVM(filePath); err != nil {
+			var l diag.List
+			if !errors.As(err, &l) {
+				return
+			}
+			diags = append(diags, l...)
+			continue
+		}
+		if optimize {
+			progs[idx] = optimizeVM(filePath, progs[idx])
+			// The lines no longer match the optimized statements.
+			srcs[idx] = nil
+		}
+		names[idx] = strings.TrimSuffix(path.Base(filePath), path.Ext(filePath))
+	}
+	if err = diags.Err(); err != nil {
+		return
+	}
+
+	return translatePrograms(names, progs, srcs, bootstrap, sourceMap)
+}
+
+// translatePrograms translates progs, each read from the file named by the
+// matching entry of names, into a single program. srcs, if not nil, holds
+// the origin of each program for sourceMap, which may be nil.
+func translatePrograms(names []string, progs []vm.Program, srcs []*vm.Source, bootstrap bool, sourceMap *vm.SourceMap) (instrs asm.Program, err error) {
+	t := &vm.Translator{SourceMap: sourceMap}
+	if bootstrap {
+		instrs = t.Bootstrap()
+	}
+
+	var progInstrs asm.Program
+	for idx, prog := range progs {
+		t.SetFile(names[idx])
+		if srcs != nil {
+			t.Source = srcs[idx]
+		}
+		if progInstrs, err = prog.Instructions(t); err != nil {
+			return
+		}
+		instrs = append(instrs, progInstrs...)
+	}
+
+	return
+}
+
+func parseVM(filePath string) (prog vm.Program, src *vm.Source, err error) {
 	var file *os.File
 	if file, err = os.Open(filePath); err != nil {
 		return
 	}
 	defer file.Close()
 
-	if prog, err = vm.Parse(file); err != nil {
+	if prog, src, err = vm.ParseSource(filePath, file, vm.AllErrors); err != nil {
 		return
 	}
 
 	return
 }
 
-func translate(filePath string, prog vm.Program) (err error) {
-	if prog == nil {
-		panic("prog is nil")
+func writeAsm(filePath string, instrs asm.Program) (err error) {
+	if instrs == nil {
+		panic("instrs is nil")
 	}
 
 	var file *os.File
@@ -54,9 +185,19 @@ func translate(filePath string, prog vm.Program) (err error) {
 	}
 	defer file.Close()
 
-	if err = prog.Translate(file); err != nil {
+	if err = instrs.Format(file); err != nil {
 		return
 	}
 
 	return
 }
+
+func writeSourceMap(filePath string, sourceMap *vm.SourceMap) (err error) {
+	var file *os.File
+	if file, err = os.Create(filePath); err != nil {
+		return
+	}
+	defer file.Close()
+
+	return sourceMap.WriteJSON(file)
+}
@@ -18,14 +18,16 @@ func AssembleString(expr Assemblable) (str string, err error) {
 }
 
 func (prog Program) Assemble(w io.Writer) (err error) {
-	prog.ResolveSymbols()
-	for idx, instr := range prog {
+	resolved, _ := prog.ResolveSymbols()
+	for idx, instr := range resolved {
 		if idx > 0 {
 			if _, err = w.Write([]byte{'\n'}); err != nil {
 				return
 			}
 		}
-		instr.Assemble(w)
+		if err = instr.Assemble(w); err != nil {
+			return
+		}
 	}
 	return
 }
@@ -48,20 +50,20 @@ func (instr *AddressInstructionConstant) Assemble(w io.Writer) (err error) {
 }
 
 func (instr *AddressInstructionSymbol) Assemble(io.Writer) error {
-	panic("AddressInstructionSymbol cannot be assembled")
+	return ErrUnresolvedSymbol{symbol: instr.Symbol}
 }
 
 func (instr *LabelInstruction) Assemble(io.Writer) (err error) {
-	panic("LabelInstruction cannot be assembled")
+	return ErrUnresolvedLabel{label: instr.Symbol}
 }
 
 func (instr *ComputeInstruction) Assemble(w io.Writer) (err error) {
-	if _, err = w.Write([]byte{'1', '1', '1'}); err != nil {
-		return
+	if instr.Comp == nil {
+		return ErrCompMissing
 	}
 
-	if instr.Comp == nil {
-		panic("ComputeInstruction.Comp is nil")
+	if _, err = w.Write([]byte{'1', '1', '1'}); err != nil {
+		return
 	}
 	if _, err = w.Write([]byte(strconv.FormatUint(uint64(instr.Comp.A()), 2))); err != nil {
 		return
@@ -121,7 +123,7 @@ func (comp Comp0) Assemble(w io.Writer) (err error) {
 	case Comp0DOrA:
 		_, err = w.Write([]byte("010101"))
 	default:
-		panic("unhandled Comp0: " + strconv.Itoa(int(comp)))
+		err = ErrCompInvalid{comp: "Comp0(" + strconv.Itoa(int(comp)) + ")"}
 	}
 
 	return
@@ -150,7 +152,7 @@ func (comp Comp1) Assemble(w io.Writer) (err error) {
 	case Comp1DOrM:
 		_, err = w.Write([]byte("010101"))
 	default:
-		panic("unhandled Comp1: " + strconv.Itoa(int(comp)))
+		err = ErrCompInvalid{comp: "Comp1(" + strconv.Itoa(int(comp)) + ")"}
 	}
 
 	return
@@ -173,7 +175,7 @@ func (dest Dest) Assemble(w io.Writer) (err error) {
 	} else {
 		m = '0'
 	}
-	w.Write([]byte{a, d, m})
+	_, err = w.Write([]byte{a, d, m})
 	return
 }
 
@@ -189,7 +191,7 @@ func (jump Jump) Assemble(w io.Writer) (err error) {
 			return
 		}
 	default:
-		panic("unhandled Jump: " + strconv.Itoa(int(jump)))
+		err = ErrJumpInvalid{jump: "Jump(" + strconv.Itoa(int(jump)) + ")"}
 	}
 
 	return
@@ -42,3 +42,21 @@ func TestAssembleAddressInstruction(t *testing.T) {
 
 	assert.Equal(t, "0000000001111011", bin)
 }
+
+func TestAssembleError(t *testing.T) {
+	t.Run("unresolved symbol", func(t *testing.T) {
+		_, err := AssembleString(&AddressInstructionSymbol{Symbol: "LOOP"})
+		assert.Equal(t, ErrUnresolvedSymbol{symbol: "LOOP"}, err)
+	})
+
+	t.Run("missing comp", func(t *testing.T) {
+		str, err := AssembleString(Program{&ComputeInstruction{Dest: DestD}})
+		assert.Equal(t, ErrCompMissing, err)
+		assert.Equal(t, "", str)
+	})
+
+	t.Run("invalid jump", func(t *testing.T) {
+		_, err := AssembleString(&ComputeInstruction{Comp: Comp00, Jump: Jump(8)})
+		assert.Equal(t, "invalid jump: Jump(8)", err.Error())
+	})
+}
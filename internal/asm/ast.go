@@ -4,12 +4,11 @@
 
 package asm
 
-import (
-	"io"
-	"slices"
-)
+import "io"
 
 type (
+	Mode uint
+
 	Assemblable interface {
 		Assemble(io.Writer) error
 	}
@@ -26,6 +25,15 @@ type (
 
 	Program []Instruction
 
+	// Source records the origin of each instruction of a parsed program.
+	Source struct {
+		File  string
+		Lines []string
+
+		// InstrLines holds the 1-based source line of each instruction.
+		InstrLines []int
+	}
+
 	AddressInstructionConstant struct {
 		Address int16
 	}
@@ -70,42 +78,6 @@ func (comp Comp1) A() uint8 {
 	return 1
 }
 
-func (prog *Program) ResolveSymbols() {
-	syms := DefaultSymbols
-	var labels []Instruction
-
-	var line int16 = 0
-	for _, instr := range *prog {
-		switch instr.(type) {
-		case *LabelInstruction:
-			labelInstr := instr.(*LabelInstruction)
-			syms[labelInstr.Symbol] = line
-			labels = append(labels, labelInstr)
-		default:
-			line += 1
-		}
-	}
-
-	var address int16 = 16
-	for i, instr := range *prog {
-		switch instr.(type) {
-		case *AddressInstructionSymbol:
-			addrInstrSym := instr.(*AddressInstructionSymbol)
-			if _, ok := syms[addrInstrSym.Symbol]; !ok {
-				syms[addrInstrSym.Symbol] = address
-				address += 1
-			}
-			(*prog)[i] = &AddressInstructionConstant{Address: syms[addrInstrSym.Symbol]}
-		}
-	}
-
-	*prog = slices.DeleteFunc(*prog, func(instr Instruction) bool {
-		return slices.Contains(labels, instr)
-	})
-
-	return
-}
-
 const (
 	Comp00 Comp0 = iota
 	Comp01
@@ -19,12 +19,12 @@ func TestResolveSymbols(t *testing.T) {
 @456
 A=M;JMP
 `)
-	prog.ResolveSymbols()
+	resolved, _ := prog.ResolveSymbols()
 	assert.Equal(t, Program{
 		&AddressInstructionConstant{Address: 123},
 		&AddressInstructionConstant{Address: 3},
 		&AddressInstructionConstant{Address: 16},
 		&AddressInstructionConstant{Address: 456},
 		&ComputeInstruction{Dest: DestA, Comp: Comp1M, Jump: JumpJMP},
-	}, prog)
+	}, resolved)
 }
@@ -6,6 +6,10 @@ package asm
 
 import "regexp"
 
+// AllErrors makes the parser report every diagnostic instead of stopping at
+// the first one.
+const AllErrors Mode = 1
+
 const (
 	SymbolR0  = "R0"
 	SymbolR1  = "R1"
@@ -34,6 +38,8 @@ const (
 	SymbolKBD    = "KBD"
 )
 
+const VariableBase = 16
+
 var (
 	AddressRegex = regexp.MustCompile("^[0-9]+$")
 	SymbolRegex  = regexp.MustCompile("^[a-zA-Z_.$:][0-9a-zA-Z_.$:]*$")
@@ -83,6 +89,7 @@ var (
 		"D-1": Comp0DMinus1,
 		"A-1": Comp0AMinus1,
 		"D+A": Comp0DPlusA,
+		"D-A": Comp0DMinusA,
 		"A-D": Comp0AMinusD,
 		"D&A": Comp0DAndA,
 		"D|A": Comp0DOrA,
@@ -114,6 +121,7 @@ var (
 		Comp0DMinus1: "D-1",
 		Comp0AMinus1: "A-1",
 		Comp0DPlusA:  "D+A",
+		Comp0DMinusA: "D-A",
 		Comp0AMinusD: "A-D",
 		Comp0DAndA:   "D&A",
 		Comp0DOrA:    "D|A",
@@ -129,6 +137,37 @@ var (
 		Comp1DOrM:    "D|M",
 	}
 
+	BinaryToComp = map[string]Comp{
+		"0101010": Comp00,
+		"0111111": Comp01,
+		"0111010": Comp0Neg1,
+		"0001100": Comp0D,
+		"0110000": Comp0A,
+		"0001101": Comp0NotD,
+		"0110001": Comp0NotA,
+		"0001111": Comp0NegD,
+		"0110011": Comp0NegA,
+		"0110111": Comp0APlus1,
+		"0011111": Comp0DPlus1,
+		"0001110": Comp0DMinus1,
+		"0110010": Comp0AMinus1,
+		"0000010": Comp0DPlusA,
+		"0010011": Comp0DMinusA,
+		"0000111": Comp0AMinusD,
+		"0000000": Comp0DAndA,
+		"0010101": Comp0DOrA,
+		"1110000": Comp1M,
+		"1110001": Comp1NotM,
+		"1110011": Comp1NegM,
+		"1110111": Comp1MPlus1,
+		"1110010": Comp1MMinus1,
+		"1000010": Comp1DPlusM,
+		"1010011": Comp1DMinusM,
+		"1000111": Comp1MMinusD,
+		"1000000": Comp1DAndM,
+		"1010101": Comp1DOrM,
+	}
+
 	StringToJump = map[string]Jump{
 		"":    JumpNull,
 		"JGT": JumpJGT,
@@ -0,0 +1,144 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package asm
+
+import (
+	"bufio"
+	"fmt"
+	"hack/internal/diag"
+	"io"
+	"strconv"
+	"strings"
+)
+
+func DisassembleString(str string) (prog Program, err error) {
+	return Disassemble(strings.NewReader(str))
+}
+
+// Disassemble parses Hack machine code, one 16-bit binary word per line, back
+// into a program. Blank lines are skipped.
+func Disassemble(r io.Reader) (prog Program, err error) {
+	return DisassembleFile("", r)
+}
+
+func DisassembleFile(name string, r io.Reader) (prog Program, err error) {
+	var line string
+	var instr Instruction
+
+	s := bufio.NewScanner(r)
+
+	for lineno := 1; s.Scan(); lineno++ {
+		if line = strings.Trim(s.Text(), " \t\r"); line == "" {
+			continue
+		}
+
+		if instr, err = DisassembleInstruction(line); err != nil {
+			err = diag.Diagnostic{
+				File:   name,
+				Line:   lineno,
+				Column: 1,
+				Source: line,
+				Err:    err,
+			}
+			return
+		}
+
+		prog = append(prog, instr)
+	}
+
+	err = s.Err()
+	return
+}
+
+func DisassembleInstruction(word string) (instr Instruction, err error) {
+	if len(word) != 16 || strings.Trim(word, "01") != "" {
+		err = ErrWordInvalid{word: word}
+		return
+	}
+
+	if word[0] == '0' {
+		var address uint64
+		if address, err = strconv.ParseUint(word[1:], 2, 15); err != nil {
+			return
+		}
+		instr = &AddressInstructionConstant{Address: int16(address)}
+		return
+	}
+
+	if word[1:3] != "11" {
+		err = ErrWordInvalid{word: word}
+		return
+	}
+
+	computeInstruction := &ComputeInstruction{}
+
+	var ok bool
+	if computeInstruction.Comp, ok = BinaryToComp[word[3:10]]; !ok {
+		err = ErrCompInvalid{comp: word[3:10]}
+		return
+	}
+
+	if word[10] == '1' {
+		computeInstruction.Dest |= DestA
+	}
+	if word[11] == '1' {
+		computeInstruction.Dest |= DestD
+	}
+	if word[12] == '1' {
+		computeInstruction.Dest |= DestM
+	}
+
+	var jump uint64
+	if jump, err = strconv.ParseUint(word[13:], 2, 3); err != nil {
+		return
+	}
+	computeInstruction.Jump = Jump(jump)
+
+	instr = computeInstruction
+	return
+}
+
+// SynthesizeLabels returns a copy of prog where every A-instruction loading a
+// jump target, that is one immediately followed by a jump, refers to a
+// generated label placed at that target.
+func (prog Program) SynthesizeLabels() (labeled Program) {
+	targets := map[int16]string{}
+	for idx := range prog {
+		if address, ok := prog.jumpTarget(idx); ok {
+			targets[address] = fmt.Sprintf("L%d", address)
+		}
+	}
+
+	for idx, instr := range prog {
+		if label, ok := targets[int16(idx)]; ok {
+			labeled = append(labeled, &LabelInstruction{Symbol: label})
+		}
+		if address, ok := prog.jumpTarget(idx); ok {
+			labeled = append(labeled, &AddressInstructionSymbol{Symbol: targets[address]})
+		} else {
+			labeled = append(labeled, instr)
+		}
+	}
+	if label, ok := targets[int16(len(prog))]; ok {
+		labeled = append(labeled, &LabelInstruction{Symbol: label})
+	}
+
+	return
+}
+
+func (prog Program) jumpTarget(idx int) (address int16, ok bool) {
+	var addrInstr *AddressInstructionConstant
+	var compInstr *ComputeInstruction
+	if addrInstr, ok = prog[idx].(*AddressInstructionConstant); !ok || idx+1 >= len(prog) {
+		return 0, false
+	}
+	if compInstr, ok = prog[idx+1].(*ComputeInstruction); !ok || compInstr.Jump == JumpNull {
+		return 0, false
+	}
+	if int(addrInstr.Address) > len(prog) {
+		return 0, false
+	}
+	return addrInstr.Address, true
+}
@@ -0,0 +1,80 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package asm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDisassemble(t *testing.T) {
+	prog, err := DisassembleString(`
+0000000000000010
+1110110000010000
+0000000000000011
+1110000010010000
+0000000000000000
+1110001100001000
+`)
+	assert.Nil(t, err)
+
+	asm, err := FormatString(prog)
+	assert.Nil(t, err)
+	assert.Equal(t, strings.Trim(`
+@2
+D=A
+@3
+D=D+A
+@0
+M=D
+`, " \t\n\r"), asm)
+}
+
+func TestDisassembleRoundTrip(t *testing.T) {
+	prog, _ := ParseString(`
+@LOOP
+AM=M-1;JGE
+D=!A
+(LOOP)
+MD=D|M;JLE
+`)
+	bin, err := AssembleString(prog)
+	assert.Nil(t, err)
+
+	disassembled, err := DisassembleString(bin)
+	assert.Nil(t, err)
+
+	resolved, _ := prog.ResolveSymbols()
+	assert.Equal(t, resolved, disassembled)
+}
+
+func TestDisassembleError(t *testing.T) {
+	_, err := DisassembleString("0000000000000010\n1000110000010000\n")
+	assert.Equal(t, "2:1: invalid machine word: 1000110000010000", err.Error())
+}
+
+func TestSynthesizeLabels(t *testing.T) {
+	prog, err := DisassembleString(`
+0000000000000011
+1110001100000001
+0000000000000000
+0000000000000011
+1110101010000111
+`)
+	assert.Nil(t, err)
+
+	asm, err := FormatString(prog.SynthesizeLabels())
+	assert.Nil(t, err)
+	assert.Equal(t, strings.Trim(`
+@L3
+D;JGT
+@0
+(L3)
+@L3
+0;JMP
+`, " \t\n\r"), asm)
+}
@@ -4,14 +4,23 @@
 
 package asm
 
-import "errors"
+import (
+	"errors"
+	"strconv"
+)
 
 var (
-	ErrAddressInstructionInvalid = errors.New("invalid address instruction")
-	ErrLabelInstructionInvalid   = errors.New("invalid label instruction")
+	ErrCompMissing    = errors.New("missing comp")
+	ErrSourceMismatch = errors.New("source does not match program")
 )
 
 type (
+	ErrAddressInstructionInvalid struct {
+		addr string
+	}
+	ErrLabelInstructionInvalid struct {
+		label string
+	}
 	ErrCompInvalid struct {
 		comp string
 	}
@@ -21,8 +30,31 @@ type (
 	ErrJumpInvalid struct {
 		jump string
 	}
+
+	ErrWordInvalid struct {
+		word string
+	}
+
+	ErrUnresolvedSymbol struct {
+		symbol string
+	}
+	ErrUnresolvedLabel struct {
+		label string
+	}
+
+	ErrJumpNumeric struct {
+		address int16
+	}
 )
 
+func (err ErrAddressInstructionInvalid) Error() string {
+	return "invalid address instruction: " + err.addr
+}
+
+func (err ErrLabelInstructionInvalid) Error() string {
+	return "invalid label instruction: " + err.label
+}
+
 func (err ErrCompInvalid) Error() string {
 	return "invalid comp: " + err.comp
 }
@@ -34,3 +66,19 @@ func (err ErrDestInvalid) Error() string {
 func (err ErrJumpInvalid) Error() string {
 	return "invalid jump: " + err.jump
 }
+
+func (err ErrWordInvalid) Error() string {
+	return "invalid machine word: " + err.word
+}
+
+func (err ErrUnresolvedSymbol) Error() string {
+	return "unresolved symbol: " + err.symbol
+}
+
+func (err ErrUnresolvedLabel) Error() string {
+	return "unresolved label: " + err.label
+}
+
+func (err ErrJumpNumeric) Error() string {
+	return "cannot optimize jump to numeric address " + strconv.Itoa(int(err.address))
+}
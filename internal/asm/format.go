@@ -7,9 +7,29 @@ package asm
 import (
 	"io"
 	"strconv"
+	"strings"
 )
 
 func FormatString(expr Formattable) (str string, err error) {
+	builder := strings.Builder{}
+	err = expr.Format(&builder)
+	str = builder.String()
+	return
+}
+
+// FormatSource formats prog one instruction per line, and returns the result
+// as the source of prog, read from the file name. It lets programs that were
+// not parsed, such as optimized ones, be listed.
+func (prog Program) FormatSource(name string) (src *Source, err error) {
+	src = &Source{File: name}
+	for idx, instr := range prog {
+		var line string
+		if line, err = FormatString(instr); err != nil {
+			return nil, err
+		}
+		src.Lines = append(src.Lines, line)
+		src.InstrLines = append(src.InstrLines, idx+1)
+	}
 	return
 }
 
@@ -20,7 +40,9 @@ func (prog Program) Format(w io.Writer) (err error) {
 				return
 			}
 		}
-		instr.Format(w)
+		if err = instr.Format(w); err != nil {
+			return
+		}
 	}
 	return
 }
@@ -59,9 +81,18 @@ func (instr *LabelInstruction) Format(w io.Writer) (err error) {
 }
 
 func (instr *ComputeInstruction) Format(w io.Writer) (err error) {
-	instr.Dest.Format(w)
-	instr.Comp.Format(w)
-	instr.Jump.Format(w)
+	if instr.Comp == nil {
+		return ErrCompMissing
+	}
+	if err = instr.Dest.Format(w); err != nil {
+		return
+	}
+	if err = instr.Comp.Format(w); err != nil {
+		return
+	}
+	if err = instr.Jump.Format(w); err != nil {
+		return
+	}
 	return
 }
 
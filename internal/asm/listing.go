@@ -0,0 +1,74 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package asm
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"text/tabwriter"
+)
+
+// Listing writes each line of src alongside the ROM address and machine word
+// of the instruction it holds, followed by the labels and variables of prog.
+// src must be the source prog was parsed from.
+func (prog Program) Listing(w io.Writer, src *Source) (err error) {
+	if len(src.InstrLines) != len(prog) {
+		return ErrSourceMismatch
+	}
+
+	resolved, syms := prog.ResolveSymbols()
+
+	words := make(map[int]string, len(resolved))
+	addresses := make(map[int]int16, len(resolved))
+	var address int16
+	for idx, instr := range prog {
+		if _, ok := instr.(*LabelInstruction); ok {
+			continue
+		}
+		line := src.InstrLines[idx]
+		if words[line], err = AssembleString(resolved[address]); err != nil {
+			return
+		}
+		addresses[line] = address
+		address += 1
+	}
+
+	for idx, raw := range src.Lines {
+		line := idx + 1
+		if word, ok := words[line]; ok {
+			_, err = fmt.Fprintf(w, "%05d  %s  %5d  %s\n", addresses[line], word, line, raw)
+		} else {
+			_, err = fmt.Fprintf(w, "%5s  %16s  %5d  %s\n", "", "", line, raw)
+		}
+		if err != nil {
+			return
+		}
+	}
+
+	if _, err = fmt.Fprintf(w, "\nSymbol table:\n"); err != nil {
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, kind := range []struct {
+		name    string
+		symbols map[string]int16
+	}{
+		{name: "label", symbols: syms.Labels},
+		{name: "variable", symbols: syms.Variables},
+	} {
+		symbols := slices.SortedFunc(maps.Keys(kind.symbols), func(a, b string) int {
+			return cmp.Or(cmp.Compare(kind.symbols[a], kind.symbols[b]), cmp.Compare(a, b))
+		})
+		for _, symbol := range symbols {
+			if _, err = fmt.Fprintf(tw, "%s\t%s\t%d\n", kind.name, symbol, kind.symbols[symbol]); err != nil {
+				return
+			}
+		}
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,61 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package asm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListing(t *testing.T) {
+	prog, src, err := ParseSource("Loop.asm", strings.NewReader(`// loop
+@i
+(LOOP)
+M=M+1 // increment
+@LOOP
+0;JMP`), 0)
+	assert.Nil(t, err)
+
+	builder := strings.Builder{}
+	assert.Nil(t, prog.Listing(&builder, src))
+	assert.Equal(t, `                             1  // loop
+00000  0000000000010000      2  @i
+                             3  (LOOP)
+00001  1111110111001000      4  M=M+1 // increment
+00002  0000000000000001      5  @LOOP
+00003  1110101010000111      6  0;JMP
+
+Symbol table:
+label     LOOP  1
+variable  i     16
+`, builder.String())
+}
+
+func TestListingFormatSource(t *testing.T) {
+	prog, err := ParseString("@SP\nM=M+1\n@SP\nAM=M-1")
+	assert.Nil(t, err)
+	optimized, err := prog.Optimize(Rules...)
+	assert.Nil(t, err)
+
+	src, err := optimized.FormatSource("Stack.asm")
+	assert.Nil(t, err)
+	assert.Equal(t, &Source{File: "Stack.asm", Lines: []string{"@SP", "A=M"}, InstrLines: []int{1, 2}}, src)
+
+	builder := strings.Builder{}
+	assert.Nil(t, optimized.Listing(&builder, src))
+	assert.Equal(t, `00000  0000000000000000      1  @SP
+00001  1111110000100000      2  A=M
+
+Symbol table:
+`, builder.String())
+}
+
+func TestListingSourceMismatch(t *testing.T) {
+	prog, _ := ParseString("@1")
+	err := prog.Listing(&strings.Builder{}, &Source{})
+	assert.Equal(t, ErrSourceMismatch, err)
+}
@@ -0,0 +1,398 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package asm
+
+type (
+	// Rule is a peephole optimization. Apply returns a program equivalent to
+	// prog, and reports whether it differs from it. prog itself is left
+	// untouched.
+	//
+	// Rules assume that jumps only target labels, never numeric addresses.
+	Rule struct {
+		Name  string
+		Apply func(prog Program) (optimized Program, changed bool)
+	}
+
+	// effects describes the registers an instruction reads and writes.
+	// Accessing M and jumping read A.
+	effects struct {
+		readA, readD, readM    bool
+		writeA, writeD, writeM bool
+		jump                   bool
+	}
+)
+
+// Rules holds every rule, in the order Optimize should apply them.
+var Rules = []Rule{
+	{Name: "redundant-loads", Apply: removeRedundantLoads},
+	{Name: "redundant-copies", Apply: removeRedundantCopies},
+	{Name: "cancel-adjustments", Apply: cancelAdjustments},
+	{Name: "dead-stores", Apply: removeDeadStores},
+	{Name: "jump-to-next", Apply: removeJumpsToNext},
+	{Name: "jump-threading", Apply: threadJumps},
+	{Name: "unreachable-code", Apply: removeUnreachableCode},
+	{Name: "unused-labels", Apply: removeUnusedLabels},
+}
+
+// Optimize applies rules in turn until none of them changes the program.
+// Variables keep the address the assembler allocates them in prog. Programs
+// jumping to numeric addresses, which the rules would move, are rejected
+// with ErrJumpNumeric.
+func (prog Program) Optimize(rules ...Rule) (optimized Program, err error) {
+	for idx, instr := range prog {
+		if addr, ok := instr.(*AddressInstructionConstant); ok && idx+1 < len(prog) {
+			if jump, ok := prog[idx+1].(*ComputeInstruction); ok && jump.Jump != JumpNull {
+				return nil, ErrJumpNumeric{address: addr.Address}
+			}
+		}
+	}
+
+	optimized = prog
+	for changed := true; changed; {
+		changed = false
+		for _, rule := range rules {
+			var ruleChanged bool
+			if optimized, ruleChanged = rule.Apply(optimized); ruleChanged {
+				changed = true
+			}
+		}
+	}
+	return pinVariables(prog, optimized), nil
+}
+
+// pinVariables replaces the references to variables of prog that the
+// assembler would allocate elsewhere in optimized, once the rules removed
+// some first references, by the address they have in prog.
+func pinVariables(prog, optimized Program) Program {
+	_, want := prog.ResolveSymbols()
+	for moved := true; moved; {
+		moved = false
+		_, got := optimized.ResolveSymbols()
+		pinned := make(Program, 0, len(optimized))
+		for _, instr := range optimized {
+			if addr, ok := instr.(*AddressInstructionSymbol); ok {
+				if address, ok := want.Variables[addr.Symbol]; ok && got.Variables[addr.Symbol] != address {
+					instr, moved = &AddressInstructionConstant{Address: address}, true
+				}
+			}
+			pinned = append(pinned, instr)
+		}
+		optimized = pinned
+	}
+	return optimized
+}
+
+// removeRedundantLoads removes A-instructions loading the value A already
+// holds, as in @SP M=M+1 @SP AM=M-1.
+func removeRedundantLoads(prog Program) (optimized Program, changed bool) {
+	// loaded is the last A-instruction of the block, as long as A still
+	// holds its value.
+	var loaded Instruction
+	for _, instr := range prog {
+		switch instr := instr.(type) {
+		case *LabelInstruction:
+			loaded = nil
+		case *AddressInstructionConstant, *AddressInstructionSymbol:
+			if loaded != nil && sameAddress(loaded, instr) {
+				changed = true
+				continue
+			}
+			loaded = instr
+		case *ComputeInstruction:
+			if instr.Dest&DestA != 0 {
+				loaded = nil
+			}
+		}
+		optimized = append(optimized, instr)
+	}
+	return
+}
+
+// removeRedundantCopies removes M=D right after D=M, and D=M right after
+// M=D.
+func removeRedundantCopies(prog Program) (optimized Program, changed bool) {
+	for idx, instr := range prog {
+		if idx > 0 && (isCompute(prog[idx-1], Comp1M, DestD) && isCompute(instr, Comp0D, DestM) ||
+			isCompute(prog[idx-1], Comp0D, DestM) && isCompute(instr, Comp1M, DestD)) {
+			changed = true
+			continue
+		}
+		optimized = append(optimized, instr)
+	}
+	return
+}
+
+// cancelAdjustments merges an increment of M followed by a decrement, or the
+// reverse, such as the stack pointer moves of a push followed by a pop.
+func cancelAdjustments(prog Program) (optimized Program, changed bool) {
+	for idx := 0; idx < len(prog); idx++ {
+		if idx+1 < len(prog) {
+			first, second := prog[idx], prog[idx+1]
+			inc := isCompute(first, Comp1MPlus1, DestM) && isCompute(second, Comp1MMinus1, DestM)
+			dec := isCompute(first, Comp1MMinus1, DestM) && isCompute(second, Comp1MPlus1, DestM)
+			incLoad := isCompute(first, Comp1MPlus1, DestM) && isCompute(second, Comp1MMinus1, DestA|DestM)
+			decLoad := isCompute(first, Comp1MMinus1, DestM) && isCompute(second, Comp1MPlus1, DestA|DestM)
+
+			switch {
+			case inc || dec:
+				idx, changed = idx+1, true
+				continue
+			case incLoad || decLoad:
+				optimized = append(optimized, &ComputeInstruction{Comp: Comp1M, Dest: DestA})
+				idx, changed = idx+1, true
+				continue
+			}
+		}
+		optimized = append(optimized, prog[idx])
+	}
+	return
+}
+
+// removeDeadStores removes instructions whose every result is overwritten by
+// the next instruction before being read, including those without any.
+func removeDeadStores(prog Program) (optimized Program, changed bool) {
+	for idx, instr := range prog {
+		if idx+1 < len(prog) && isDead(instr, prog[idx+1]) {
+			changed = true
+			continue
+		}
+		optimized = append(optimized, instr)
+	}
+	return
+}
+
+// removeJumpsToNext removes jumps without side effects whose target label
+// directly follows them, as long as the code after the label loads A before
+// reading it.
+func removeJumpsToNext(prog Program) (optimized Program, changed bool) {
+	for idx := 0; idx < len(prog); idx++ {
+		if label, ok := jumpLabel(prog, idx); ok && prog[idx+1].(*ComputeInstruction).Dest == DestNull &&
+			labelFollows(prog, idx+2, label) && loadsA(prog, idx+2) {
+			idx, changed = idx+1, true
+			continue
+		}
+		optimized = append(optimized, prog[idx])
+	}
+	return
+}
+
+// threadJumps retargets jumps to a label whose code is an unconditional jump
+// to another one, as in @A 0;JMP ... (A) @B 0;JMP.
+func threadJumps(prog Program) (optimized Program, changed bool) {
+	forward := map[string]string{}
+	for idx, instr := range prog {
+		label, ok := instr.(*LabelInstruction)
+		if !ok {
+			continue
+		}
+		next := idx + 1
+		for next < len(prog) {
+			if _, ok := prog[next].(*LabelInstruction); !ok {
+				break
+			}
+			next += 1
+		}
+		if target, ok := jumpLabel(prog, next); ok {
+			if jump := prog[next+1].(*ComputeInstruction); jump.Dest == DestNull && jump.Jump == JumpJMP {
+				forward[label.Symbol] = target
+			}
+		}
+	}
+
+	for idx, instr := range prog {
+		if label, ok := jumpLabel(prog, idx); ok && fallthroughIgnoresA(prog, idx+1) {
+			if target := resolveForward(forward, label); target != label {
+				instr, changed = &AddressInstructionSymbol{Symbol: target}, true
+			}
+		}
+		optimized = append(optimized, instr)
+	}
+	return
+}
+
+// removeUnreachableCode removes the instructions between an unconditional
+// jump and the next label.
+func removeUnreachableCode(prog Program) (optimized Program, changed bool) {
+	reachable := true
+	for _, instr := range prog {
+		switch instr := instr.(type) {
+		case *LabelInstruction:
+			reachable = true
+		case *ComputeInstruction:
+			if !reachable {
+				changed = true
+				continue
+			}
+			if instr.Jump == JumpJMP {
+				optimized = append(optimized, instr)
+				reachable = false
+				continue
+			}
+		default:
+			if !reachable {
+				changed = true
+				continue
+			}
+		}
+		optimized = append(optimized, instr)
+	}
+	return
+}
+
+// removeUnusedLabels removes the labels no A-instruction refers to, which
+// lets the other rules work across them.
+func removeUnusedLabels(prog Program) (optimized Program, changed bool) {
+	used := map[string]bool{}
+	for _, instr := range prog {
+		if instr, ok := instr.(*AddressInstructionSymbol); ok {
+			used[instr.Symbol] = true
+		}
+	}
+
+	for _, instr := range prog {
+		if instr, ok := instr.(*LabelInstruction); ok && !used[instr.Symbol] {
+			changed = true
+			continue
+		}
+		optimized = append(optimized, instr)
+	}
+	return
+}
+
+// jumpLabel returns the label loaded by prog[idx] if it is an A-instruction
+// only used as the target of the jump following it.
+func jumpLabel(prog Program, idx int) (label string, ok bool) {
+	if idx+1 >= len(prog) {
+		return "", false
+	}
+	addr, ok := prog[idx].(*AddressInstructionSymbol)
+	if !ok {
+		return "", false
+	}
+	jump, ok := prog[idx+1].(*ComputeInstruction)
+	if !ok || jump.Jump == JumpNull || jump.Dest&DestM != 0 || compReads(jump.Comp).readA {
+		return "", false
+	}
+	return addr.Symbol, true
+}
+
+// labelFollows reports whether label is among the labels starting at
+// prog[idx].
+func labelFollows(prog Program, idx int, label string) bool {
+	for ; idx < len(prog); idx++ {
+		instr, ok := prog[idx].(*LabelInstruction)
+		if !ok {
+			return false
+		}
+		if instr.Symbol == label {
+			return true
+		}
+	}
+	return false
+}
+
+// fallthroughIgnoresA reports whether the code following the jump at
+// prog[idx] does not read A, so that the jump target may change.
+func fallthroughIgnoresA(prog Program, idx int) bool {
+	return prog[idx].(*ComputeInstruction).Jump == JumpJMP || loadsA(prog, idx+1)
+}
+
+// loadsA reports whether the code starting at prog[idx], labels aside, writes
+// A before reading it, or ends.
+func loadsA(prog Program, idx int) bool {
+	for ; idx < len(prog); idx++ {
+		if _, ok := prog[idx].(*LabelInstruction); ok {
+			continue
+		}
+		e, _ := effectsOf(prog[idx])
+		return e.writeA && !e.readA
+	}
+	return true
+}
+
+// resolveForward follows forward from label to the final target, leaving
+// label as is if it leads to a cycle.
+func resolveForward(forward map[string]string, label string) string {
+	seen := map[string]bool{label: true}
+	target := label
+	for {
+		next, ok := forward[target]
+		if !ok {
+			return target
+		}
+		if seen[next] {
+			return label
+		}
+		seen[next] = true
+		target = next
+	}
+}
+
+// isDead reports whether everything instr computes is overwritten by next
+// before being read.
+func isDead(instr, next Instruction) bool {
+	e, ok := effectsOf(instr)
+	if !ok || e.jump {
+		return false
+	}
+	n, ok := effectsOf(next)
+	if !ok {
+		return false
+	}
+	return (!e.writeA || n.writeA && !n.readA) &&
+		(!e.writeD || n.writeD && !n.readD) &&
+		(!e.writeM || !e.writeA && n.writeM && !n.readM)
+}
+
+// effectsOf returns the effects of instr, if it is not a label.
+func effectsOf(instr Instruction) (e effects, ok bool) {
+	switch instr := instr.(type) {
+	case *AddressInstructionConstant, *AddressInstructionSymbol:
+		return effects{writeA: true}, true
+	case *ComputeInstruction:
+		e = compReads(instr.Comp)
+		e.writeA = instr.Dest&DestA != 0
+		e.writeD = instr.Dest&DestD != 0
+		e.writeM = instr.Dest&DestM != 0
+		e.jump = instr.Jump != JumpNull
+		e.readA = e.readA || e.writeM || e.jump
+		return e, true
+	}
+	return effects{}, false
+}
+
+// compReads returns the registers comp reads.
+func compReads(comp Comp) (e effects) {
+	switch comp {
+	case Comp0D, Comp0NotD, Comp0NegD, Comp0DPlus1, Comp0DMinus1:
+		e.readD = true
+	case Comp0A, Comp0NotA, Comp0NegA, Comp0APlus1, Comp0AMinus1:
+		e.readA = true
+	case Comp0DPlusA, Comp0DMinusA, Comp0AMinusD, Comp0DAndA, Comp0DOrA:
+		e.readA, e.readD = true, true
+	case Comp1M, Comp1NotM, Comp1NegM, Comp1MPlus1, Comp1MMinus1:
+		e.readA, e.readM = true, true
+	case Comp1DPlusM, Comp1DMinusM, Comp1MMinusD, Comp1DAndM, Comp1DOrM:
+		e.readA, e.readD, e.readM = true, true, true
+	}
+	return
+}
+
+func isCompute(instr Instruction, comp Comp, dest Dest) bool {
+	compute, ok := instr.(*ComputeInstruction)
+	return ok && compute.Comp == comp && compute.Dest == dest && compute.Jump == JumpNull
+}
+
+func sameAddress(a, b Instruction) bool {
+	switch a := a.(type) {
+	case *AddressInstructionConstant:
+		b, ok := b.(*AddressInstructionConstant)
+		return ok && a.Address == b.Address
+	case *AddressInstructionSymbol:
+		b, ok := b.(*AddressInstructionSymbol)
+		return ok && a.Symbol == b.Symbol
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package asm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOptimizeRules(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		rule     func(Program) (Program, bool)
+		src, out string
+	}{
+		{"redundant load", removeRedundantLoads, "@SP\nM=M+1\n@SP\nAM=M-1", "@SP\nM=M+1\nAM=M-1"},
+		{"load after A changes", removeRedundantLoads, "@SP\nA=M\n@SP\nM=0", "@SP\nA=M\n@SP\nM=0"},
+		{"load after label", removeRedundantLoads, "@SP\n(L)\n@SP\nM=0", "@SP\n(L)\n@SP\nM=0"},
+		{"load after conditional jump", removeRedundantLoads, "@L\nD;JGT\n@L\n0;JMP", "@L\nD;JGT\n0;JMP"},
+		{"copy back", removeRedundantCopies, "D=M\nM=D\nM=D+1", "D=M\nM=D+1"},
+		{"load back", removeRedundantCopies, "M=D\nD=M", "M=D"},
+		{"increment then decrement", cancelAdjustments, "@SP\nM=M+1\nM=M-1\nD=M", "@SP\nD=M"},
+		{"push then pop", cancelAdjustments, "@SP\nM=M+1\nAM=M-1\nD=M", "@SP\nA=M\nD=M"},
+		{"overwritten D", removeDeadStores, "D=A\nD=0", "D=0"},
+		{"overwritten A", removeDeadStores, "@1\n@2\nD=A", "@2\nD=A"},
+		{"overwritten M", removeDeadStores, "M=D\nM=0", "M=0"},
+		{"read D", removeDeadStores, "D=A\nD=D+1", "D=A\nD=D+1"},
+		{"M at another address", removeDeadStores, "AM=D\nM=0", "AM=D\nM=0"},
+		{"address", removeDeadStores, "@1\nM=0", "@1\nM=0"},
+		{"no-op", removeDeadStores, "D\n@1", "@1"},
+		{"jump", removeDeadStores, "D;JGT\nD=0", "D;JGT\nD=0"},
+		{"jump to next", removeJumpsToNext, "@L\nD;JEQ\n(K)\n(L)\n@R0\nM=0", "(K)\n(L)\n@R0\nM=0"},
+		{"jump to next reading A", removeJumpsToNext, "@L\nD;JEQ\n(L)\nM=0", "@L\nD;JEQ\n(L)\nM=0"},
+		{"jump over", removeJumpsToNext, "@L\n0;JMP\nM=0\n(L)", "@L\n0;JMP\nM=0\n(L)"},
+		{"jump with side effect", removeJumpsToNext, "@L\nD=D-1;JEQ\n(L)", "@L\nD=D-1;JEQ\n(L)"},
+		{"thread", threadJumps, "@A\n0;JMP\n(A)\n@B\n0;JMP\n(B)\n@C\n0;JMP", "@C\n0;JMP\n(A)\n@C\n0;JMP\n(B)\n@C\n0;JMP"},
+		{"thread conditional", threadJumps, "@A\nD;JGT\n@X\n(A)\n@B\n0;JMP", "@B\nD;JGT\n@X\n(A)\n@B\n0;JMP"},
+		{"A used after label", threadJumps, "@A\nD;JGT\n(X)\nM=0\n(A)\n@B\n0;JMP", "@A\nD;JGT\n(X)\nM=0\n(A)\n@B\n0;JMP"},
+		{"A used on fallthrough", threadJumps, "@A\nD;JGT\nM=0\n(A)\n@B\n0;JMP", "@A\nD;JGT\nM=0\n(A)\n@B\n0;JMP"},
+		{"cycle", threadJumps, "(A)\n@B\n0;JMP\n(B)\n@A\n0;JMP", "(A)\n@B\n0;JMP\n(B)\n@A\n0;JMP"},
+		{"unreachable", removeUnreachableCode, "@L\n0;JMP\n@1\nM=0\n(L)\nM=1", "@L\n0;JMP\n(L)\nM=1"},
+		{"unused label", removeUnusedLabels, "(A)\n(B)\n@B", "(B)\n@B"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			prog, err := ParseString(tc.src)
+			assert.Nil(t, err)
+
+			optimized, changed := tc.rule(prog)
+			str, err := FormatString(optimized)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.out, str)
+			assert.Equal(t, tc.src != tc.out, changed)
+		})
+	}
+}
+
+func TestOptimize(t *testing.T) {
+	prog, err := ParseString(`@x
+M=0
+@SKIP
+0;JMP
+@y
+M=1
+(SKIP)
+@z
+M=D
+D=M
+@SP
+M=M+1
+@SP
+AM=M-1
+(END)
+@END
+0;JMP`)
+	assert.Nil(t, err)
+
+	optimized, err := prog.Optimize(Rules...)
+	assert.Nil(t, err)
+	str, err := FormatString(optimized)
+	assert.Nil(t, err)
+	// y is gone, so z keeps its address instead of taking the one of y.
+	assert.Equal(t, `@x
+M=0
+@18
+M=D
+@SP
+A=M
+(END)
+@END
+0;JMP`, str)
+}
+
+func TestOptimizeJumpNumeric(t *testing.T) {
+	prog, err := ParseString("@3\n0;JMP")
+	assert.Nil(t, err)
+
+	_, err = prog.Optimize(Rules...)
+	assert.Equal(t, ErrJumpNumeric{address: 3}, err)
+}
@@ -6,6 +6,7 @@ package asm
 
 import (
 	"bufio"
+	"hack/internal/diag"
 	"io"
 	"strconv"
 	"strings"
@@ -16,35 +17,69 @@ func ParseString(str string) (prog Program, err error) {
 }
 
 func Parse(r io.Reader) (prog Program, err error) {
-	var line string
+	return ParseFile("", r, 0)
+}
+
+func ParseFile(name string, r io.Reader, mode Mode) (prog Program, err error) {
+	prog, _, err = ParseSource(name, r, mode)
+	return
+}
+
+// ParseSource parses r like ParseFile and also returns where each instruction
+// of prog comes from.
+func ParseSource(name string, r io.Reader, mode Mode) (prog Program, src *Source, err error) {
+	var diags diag.List
+	var raw, line string
 	var instr Instruction
 
+	src = &Source{File: name}
 	s := bufio.NewScanner(r)
 
-	for s.Scan() {
-		line = strings.Trim(s.Text(), " \t\r")
+	for lineno := 1; s.Scan(); lineno++ {
+		raw = strings.TrimRight(s.Text(), "\r")
+		src.Lines = append(src.Lines, raw)
+		line, _, _ = strings.Cut(raw, "//")
+		line = strings.Trim(line, " \t")
 
+		// offset is that of the offending token in line.
+		var offset int
 		switch {
-		case line == "" || strings.HasPrefix(line, "//"):
+		case line == "":
 			continue
 		case strings.HasPrefix(line, "@"):
-			if instr, err = ParseAddressInstruction(line); err != nil {
-				return
-			}
+			instr, err = ParseAddressInstruction(line)
+			offset = 1
 		case strings.HasPrefix(line, "(") && strings.HasSuffix(line, ")"):
-			if instr, err = ParseLabelInstruction(line); err != nil {
-				return
-			}
+			instr, err = ParseLabelInstruction(line)
+			offset = 1
 		default:
-			if instr, err = ParseComputeInstruction(line); err != nil {
+			instr, offset, err = parseComputeInstruction(line)
+		}
+
+		if err != nil {
+			indent := len(raw) - len(strings.TrimLeft(raw, " \t"))
+			diags = append(diags, diag.Diagnostic{
+				File:   name,
+				Line:   lineno,
+				Column: indent + offset + 1,
+				Source: raw,
+				Err:    err,
+			})
+			if mode&AllErrors == 0 {
+				err = diags[0]
 				return
 			}
+			continue
 		}
 
 		prog = append(prog, instr)
+		src.InstrLines = append(src.InstrLines, lineno)
 	}
 
-	err = s.Err()
+	if err = s.Err(); err != nil {
+		return
+	}
+	err = diags.Err()
 	return
 }
 
@@ -52,15 +87,16 @@ func ParseAddressInstruction(line string) (instr Instruction, err error) {
 	addrOrSym := strings.TrimPrefix(line, "@")
 
 	if AddressRegex.MatchString(addrOrSym) {
-		var address int
-		if address, err = strconv.Atoi(addrOrSym); err != nil {
+		var address uint64
+		if address, err = strconv.ParseUint(addrOrSym, 10, 15); err != nil {
+			err = ErrAddressInstructionInvalid{addr: addrOrSym}
 			return
 		}
 		instr = &AddressInstructionConstant{Address: int16(address)}
 	} else if SymbolRegex.MatchString(addrOrSym) {
 		instr = &AddressInstructionSymbol{Symbol: addrOrSym}
 	} else {
-		err = ErrAddressInstructionInvalid
+		err = ErrAddressInstructionInvalid{addr: addrOrSym}
 	}
 
 	return
@@ -73,29 +109,40 @@ func ParseLabelInstruction(line string) (instr Instruction, err error) {
 	if SymbolRegex.MatchString(symbol) {
 		instr = &LabelInstruction{Symbol: symbol}
 	} else {
-		err = ErrLabelInstructionInvalid
+		err = ErrLabelInstructionInvalid{label: symbol}
 	}
 
 	return
 }
 
 func ParseComputeInstruction(line string) (instr Instruction, err error) {
+	instr, _, err = parseComputeInstruction(line)
+	return
+}
+
+// parseComputeInstruction parses line like ParseComputeInstruction, and
+// returns the offset in line of the part an error is about.
+func parseComputeInstruction(line string) (instr Instruction, offset int, err error) {
 	computeInstruction := &ComputeInstruction{}
 
-	var before, after string
-	var found bool
-	if before, after, found = strings.Cut(line, "="); !found {
-		after = before
-		before = ""
+	dest, comp, found := strings.Cut(line, "=")
+	if !found {
+		dest, comp = "", dest
 	}
-	if computeInstruction.Dest, err = ParseComputeInstructionDest(before); err != nil {
+	if computeInstruction.Dest, err = ParseComputeInstructionDest(dest); err != nil {
 		return
 	}
-	before, after, _ = strings.Cut(after, ";")
-	if computeInstruction.Comp, err = ParseComputeInstructionComp(before); err != nil {
+	if found {
+		offset = len(dest) + 1
+	}
+	comp, jump, found := strings.Cut(comp, ";")
+	if computeInstruction.Comp, err = ParseComputeInstructionComp(comp); err != nil {
 		return
 	}
-	if computeInstruction.Jump, err = ParseComputeInstructionJump(after); err != nil {
+	if found {
+		offset += len(comp) + 1
+	}
+	if computeInstruction.Jump, err = ParseComputeInstructionJump(jump); err != nil {
 		return
 	}
 
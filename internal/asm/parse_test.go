@@ -5,6 +5,8 @@
 package asm
 
 import (
+	"hack/internal/diag"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -69,9 +71,52 @@ func TestParseComputeInstruction(t *testing.T) {
 		assert.Equal(t, &ComputeInstruction{Comp: Comp1M, Jump: JumpJMP}, instr)
 	})
 
+	t.Run("dest+comp d-a", func(t *testing.T) {
+		instr, err := ParseComputeInstruction("A=D-A")
+		assert.Nil(t, err)
+		assert.Equal(t, &ComputeInstruction{Dest: DestA, Comp: Comp0DMinusA}, instr)
+	})
+
 	t.Run("dest+comp+jump", func(t *testing.T) {
 		instr, err := ParseComputeInstruction("A=M;JMP")
 		assert.Nil(t, err)
 		assert.Equal(t, &ComputeInstruction{Dest: DestA, Comp: Comp1M, Jump: JumpJMP}, instr)
 	})
 }
+
+func TestParseError(t *testing.T) {
+	t.Run("comp", func(t *testing.T) {
+		_, err := ParseFile("Bad.asm", strings.NewReader("@1\n  D=X;JMP // comment\n"), 0)
+		assert.Equal(t, diag.Diagnostic{
+			File:   "Bad.asm",
+			Line:   2,
+			Column: 5,
+			Source: "  D=X;JMP // comment",
+			Err:    ErrCompInvalid{comp: "X"},
+		}, err)
+	})
+
+	t.Run("comp matching the dest", func(t *testing.T) {
+		_, err := ParseString("AD=AD")
+		assert.Equal(t, "1:4: invalid comp: AD", err.Error())
+	})
+
+	t.Run("jump", func(t *testing.T) {
+		_, err := ParseString("JMQ=D;JMQ")
+		assert.Equal(t, "1:7: invalid jump: JMQ", err.Error())
+	})
+
+	t.Run("address", func(t *testing.T) {
+		_, err := ParseString("\n@1abc")
+		assert.Equal(t, "2:2: invalid address instruction: 1abc", err.Error())
+	})
+}
+
+func TestParseAllErrors(t *testing.T) {
+	prog, err := ParseFile("Bad.asm", strings.NewReader("D=X\n@1\n0;JMQ\n"), AllErrors)
+	assert.Equal(t, Program{&AddressInstructionConstant{Address: 1}}, prog)
+	assert.Equal(t, diag.List{
+		{File: "Bad.asm", Line: 1, Column: 3, Source: "D=X", Err: ErrCompInvalid{comp: "X"}},
+		{File: "Bad.asm", Line: 3, Column: 3, Source: "0;JMQ", Err: ErrJumpInvalid{jump: "JMQ"}},
+	}, err)
+}
@@ -0,0 +1,82 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package asm
+
+import "maps"
+
+type (
+	// SymbolTable maps the symbols of a program to addresses. Labels are ROM
+	// addresses, predefined symbols and variables are RAM addresses.
+	SymbolTable struct {
+		Predefined map[string]int16
+		Labels     map[string]int16
+		Variables  map[string]int16
+
+		// Next is the RAM address of the next allocated variable.
+		Next int16
+	}
+)
+
+func NewSymbolTable() *SymbolTable {
+	return &SymbolTable{
+		Predefined: maps.Clone(DefaultSymbols),
+		Labels:     map[string]int16{},
+		Variables:  map[string]int16{},
+		Next:       VariableBase,
+	}
+}
+
+func (syms *SymbolTable) Lookup(symbol string) (address int16, ok bool) {
+	if address, ok = syms.Labels[symbol]; ok {
+		return
+	}
+	if address, ok = syms.Predefined[symbol]; ok {
+		return
+	}
+	address, ok = syms.Variables[symbol]
+	return
+}
+
+// Resolve returns the address of symbol, allocating a new variable if it is
+// not yet defined.
+func (syms *SymbolTable) Resolve(symbol string) int16 {
+	if address, ok := syms.Lookup(symbol); ok {
+		return address
+	}
+	address := syms.Next
+	syms.Variables[symbol] = address
+	syms.Next += 1
+	return address
+}
+
+// ResolveSymbols returns a copy of prog where labels are removed and every
+// symbol is replaced by its address, along with the symbol table built to do
+// so. prog itself is left untouched.
+func (prog Program) ResolveSymbols() (resolved Program, syms *SymbolTable) {
+	syms = NewSymbolTable()
+
+	var line int16 = 0
+	for _, instr := range prog {
+		switch instr := instr.(type) {
+		case *LabelInstruction:
+			syms.Labels[instr.Symbol] = line
+		default:
+			line += 1
+		}
+	}
+
+	resolved = make(Program, 0, line)
+	for _, instr := range prog {
+		switch instr := instr.(type) {
+		case *LabelInstruction:
+		case *AddressInstructionSymbol:
+			resolved = append(resolved, &AddressInstructionConstant{Address: syms.Resolve(instr.Symbol)})
+		default:
+			resolved = append(resolved, instr)
+		}
+	}
+
+	return
+}
@@ -0,0 +1,79 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package asm
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveSymbolsTable(t *testing.T) {
+	prog, _ := ParseString(`
+@i
+(LOOP)
+@j
+@LOOP
+@i
+(END)
+@SCREEN
+`)
+	_, syms := prog.ResolveSymbols()
+	assert.Equal(t, map[string]int16{"LOOP": 1, "END": 4}, syms.Labels)
+	assert.Equal(t, map[string]int16{"i": 16, "j": 17}, syms.Variables)
+	assert.Equal(t, int16(18), syms.Next)
+
+	_, ok := DefaultSymbols["i"]
+	assert.False(t, ok)
+	_, ok = DefaultSymbols["LOOP"]
+	assert.False(t, ok)
+}
+
+func TestResolveSymbolsUnmodified(t *testing.T) {
+	prog, _ := ParseString(`
+@i
+(LOOP)
+@LOOP
+`)
+	original := append(Program{}, prog...)
+
+	prog.ResolveSymbols()
+	assert.Equal(t, original, prog)
+}
+
+func TestAssembleConcurrent(t *testing.T) {
+	progs := []string{`
+@a
+@b
+(LOOP)
+@LOOP
+`, `
+(START)
+@x
+@START
+`}
+
+	expected := make([]string, len(progs))
+	for i, src := range progs {
+		prog, _ := ParseString(src)
+		expected[i], _ = AssembleString(prog)
+	}
+
+	var wg sync.WaitGroup
+	for range 8 {
+		for i, src := range progs {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				prog, _ := ParseString(src)
+				bin, err := AssembleString(prog)
+				assert.Nil(t, err)
+				assert.Equal(t, expected[i], bin)
+			}()
+		}
+	}
+	wg.Wait()
+}
@@ -0,0 +1,168 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cpu
+
+import (
+	"hack/internal/asm"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type (
+	// CPU is a Hack computer: the CPU registers, the instruction memory and
+	// the data memory, which maps the screen and the keyboard.
+	CPU struct {
+		A  int16
+		D  int16
+		PC uint16
+
+		ROM [ROMSize]uint16
+		RAM [RAMSize]int16
+
+		// Cycles counts the instructions executed since the last reset.
+		Cycles int
+	}
+)
+
+func New() *CPU {
+	return &CPU{}
+}
+
+// Load assembles prog into ROM and resets the CPU.
+func (cpu *CPU) Load(prog asm.Program) (err error) {
+	var bin string
+	if bin, err = asm.AssembleString(prog); err != nil {
+		return
+	}
+
+	words := strings.Fields(bin)
+	if len(words) > ROMSize {
+		return ErrROMOverflow
+	}
+	for address, word := range words {
+		var value uint64
+		if value, err = strconv.ParseUint(word, 2, 16); err != nil {
+			return
+		}
+		cpu.ROM[address] = uint16(value)
+	}
+
+	clear(cpu.ROM[len(words):])
+	cpu.Reset()
+	return
+}
+
+// LoadHack loads Hack machine code, one binary word per line, into ROM and
+// resets the CPU.
+func (cpu *CPU) LoadHack(r io.Reader) (err error) {
+	var prog asm.Program
+	if prog, err = asm.Disassemble(r); err != nil {
+		return
+	}
+	return cpu.Load(prog)
+}
+
+// Reset sets PC to 0 and clears the cycle counter. Registers and RAM are left
+// untouched, like the Hack reset input.
+func (cpu *CPU) Reset() {
+	cpu.PC = 0
+	cpu.Cycles = 0
+}
+
+// Step executes the instruction at PC. PC has 15 bits, like the addresses of
+// the ROM, so it wraps around past the end of the ROM.
+func (cpu *CPU) Step() {
+	pc := cpu.PC % ROMSize
+	instr := cpu.ROM[pc]
+	cpu.Cycles += 1
+
+	if instr&bitC == 0 {
+		cpu.A = int16(instr)
+		cpu.PC = (pc + 1) % ROMSize
+		return
+	}
+
+	address := uint16(cpu.A) % RAMSize
+
+	y := cpu.A
+	if instr&bitA != 0 {
+		y = cpu.RAM[address]
+	}
+	out := alu(cpu.D, y, instr)
+
+	if instr&bitDestM != 0 {
+		cpu.RAM[address] = out
+	}
+	if instr&bitDestD != 0 {
+		cpu.D = out
+	}
+
+	jump := (instr&bitJLT != 0 && out < 0) ||
+		(instr&bitJEQ != 0 && out == 0) ||
+		(instr&bitJGT != 0 && out > 0)
+	if jump {
+		cpu.PC = uint16(cpu.A) % ROMSize
+	} else {
+		cpu.PC = (pc + 1) % ROMSize
+	}
+
+	if instr&bitDestA != 0 {
+		cpu.A = out
+	}
+}
+
+// Run executes n instructions.
+func (cpu *CPU) Run(n int) {
+	for range n {
+		cpu.Step()
+	}
+}
+
+// RunUntil executes instructions until done returns true or n instructions
+// have been executed, and reports whether done returned true.
+func (cpu *CPU) RunUntil(n int, done func(cpu *CPU) bool) bool {
+	for range n {
+		if done(cpu) {
+			return true
+		}
+		cpu.Step()
+	}
+	return done(cpu)
+}
+
+// Screen returns the memory map of the screen, one bit per pixel.
+func (cpu *CPU) Screen() []int16 {
+	return cpu.RAM[ScreenBase : ScreenBase+ScreenSize]
+}
+
+// SetKey sets the code of the key currently pressed, 0 for none.
+func (cpu *CPU) SetKey(key int16) {
+	cpu.RAM[KeyboardAddress] = key
+}
+
+func alu(x, y int16, instr uint16) (out int16) {
+	if instr&bitZX != 0 {
+		x = 0
+	}
+	if instr&bitNX != 0 {
+		x = ^x
+	}
+	if instr&bitZY != 0 {
+		y = 0
+	}
+	if instr&bitNY != 0 {
+		y = ^y
+	}
+	if instr&bitF != 0 {
+		out = x + y
+	} else {
+		out = x & y
+	}
+	if instr&bitNO != 0 {
+		out = ^out
+	}
+	return
+}
@@ -0,0 +1,163 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cpu
+
+import (
+	"hack/internal/asm"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func loadFile(t *testing.T, cpu *CPU, filePath string) {
+	file, err := os.Open(filePath)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	defer file.Close()
+
+	prog, err := asm.Parse(file)
+	assert.Nil(t, err)
+	assert.Nil(t, cpu.Load(prog))
+}
+
+func TestMult(t *testing.T) {
+	for _, tc := range []struct{ r0, r1, r2 int16 }{
+		{0, 0, 0},
+		{1, 0, 0},
+		{0, 2, 0},
+		{3, 1, 3},
+		{2, 4, 8},
+		{6, 7, 42},
+	} {
+		cpu := New()
+		loadFile(t, cpu, "../../projects/04/mult/Mult.asm")
+		cpu.RAM[0] = tc.r0
+		cpu.RAM[1] = tc.r1
+		cpu.RAM[2] = -1
+		cpu.Run(210)
+		assert.Equal(t, tc.r2, cpu.RAM[2])
+	}
+}
+
+func TestLoadHack(t *testing.T) {
+	file, err := os.Open("../../projects/05/Add.hack")
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	defer file.Close()
+
+	cpu := New()
+	assert.Nil(t, cpu.LoadHack(file))
+	cpu.Run(6)
+	assert.Equal(t, int16(5), cpu.RAM[0])
+	assert.Equal(t, 6, cpu.Cycles)
+}
+
+func TestLoadHackError(t *testing.T) {
+	err := New().LoadHack(strings.NewReader("0102"))
+	assert.ErrorAs(t, err, &asm.ErrWordInvalid{})
+	assert.EqualError(t, err, "1:1: invalid machine word: 0102")
+}
+
+func TestStep(t *testing.T) {
+	prog, _ := asm.ParseString(`
+@100
+D=-A
+@SCREEN
+AM=D-1
+D=D|A;JLT
+`)
+	cpu := New()
+	assert.Nil(t, cpu.Load(prog))
+
+	cpu.Run(2)
+	assert.Equal(t, int16(-100), cpu.D)
+	cpu.Run(2)
+	assert.Equal(t, int16(-101), cpu.A)
+	assert.Equal(t, int16(-101), cpu.Screen()[0])
+	cpu.Step()
+	assert.Equal(t, int16(-101|-100), cpu.D)
+	assert.Equal(t, uint16(0x7f9b), cpu.PC)
+
+	cpu.PC = 40000
+	cpu.Step()
+	assert.Equal(t, uint16(40000%ROMSize+1), cpu.PC)
+}
+
+func TestRunUntil(t *testing.T) {
+	prog, _ := asm.ParseString(`
+@KBD
+D=M
+@0
+D;JEQ
+@R0
+M=D
+(END)
+@END
+0;JMP
+`)
+	cpu := New()
+	assert.Nil(t, cpu.Load(prog))
+
+	assert.False(t, cpu.RunUntil(100, func(cpu *CPU) bool { return cpu.PC == 6 }))
+	cpu.SetKey(65)
+	assert.True(t, cpu.RunUntil(100, func(cpu *CPU) bool { return cpu.PC == 6 }))
+	assert.Equal(t, int16(65), cpu.RAM[0])
+}
+
+func TestOptimize(t *testing.T) {
+	for _, filePath := range []string{
+		"../../projects/04/mult/Mult.asm",
+		"../../projects/06/max/Max.asm",
+		"../../projects/06/rect/Rect.asm",
+	} {
+		file, err := os.Open(filePath)
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+		prog, err := asm.Parse(file)
+		file.Close()
+		assert.Nil(t, err)
+		optimized, err := prog.Optimize(asm.Rules...)
+		assert.Nil(t, err)
+
+		original, cpu := New(), New()
+		assert.Nil(t, original.Load(prog))
+		assert.Nil(t, cpu.Load(optimized))
+		for _, c := range []*CPU{original, cpu} {
+			c.RAM[0], c.RAM[1] = 6, 7
+			c.Run(1000)
+		}
+		assert.Equal(t, original.RAM, cpu.RAM, filePath)
+	}
+}
+
+func TestOptimizeHandWritten(t *testing.T) {
+	// The code after (L) stores through the A the jump left, the address of L.
+	prog, err := asm.ParseString(`@R0
+D=M
+@L
+D;JEQ
+(L)
+M=1
+(END)
+@END
+0;JMP`)
+	assert.Nil(t, err)
+	optimized, err := prog.Optimize(asm.Rules...)
+	assert.Nil(t, err)
+
+	original, cpu := New(), New()
+	assert.Nil(t, original.Load(prog))
+	assert.Nil(t, cpu.Load(optimized))
+	for _, c := range []*CPU{original, cpu} {
+		c.Run(100)
+	}
+	assert.Equal(t, int16(1), original.RAM[4])
+	assert.Equal(t, original.RAM, cpu.RAM)
+}
@@ -0,0 +1,11 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cpu
+
+import "errors"
+
+var (
+	ErrROMOverflow = errors.New("program does not fit in ROM")
+)
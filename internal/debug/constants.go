@@ -0,0 +1,35 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debug
+
+const (
+	Prompt = "(hack) "
+
+	// DefaultLimit is the number of instructions continue executes at most
+	// before giving control back.
+	DefaultLimit = 10_000_000
+
+	// haltJump is the machine word of 0;JMP.
+	haltJump = 0b1110101010000111
+
+	// listContext is the number of source lines list shows around PC.
+	listContext = 5
+)
+
+const help = `Commands:
+  break LABEL|ADDR       stop before executing the instruction at a ROM address
+  delete [LABEL|ADDR]    remove a breakpoint, or all of them
+  watch SYMBOL|ADDR      stop when a RAM word changes
+  unwatch [SYMBOL|ADDR]  remove a watchpoint, or all of them
+  info                   list breakpoints and watchpoints
+  step [N]               execute N instructions, 1 by default
+  continue [N]           execute until a breakpoint, a watchpoint or the end
+  regs                   print A, D, PC and M
+  print SYMBOL|ADDR      print a RAM word
+  x ADDR [N]             print N RAM words from ADDR
+  set REG|SYMBOL|ADDR N  set A, D, PC or a RAM word
+  list                   print the source around PC
+  reset                  set PC to 0 and clear the cycle counter
+  quit                   exit the debugger`
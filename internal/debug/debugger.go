@@ -0,0 +1,370 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debug
+
+import (
+	"bufio"
+	"fmt"
+	"hack/internal/asm"
+	"hack/internal/cpu"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type (
+	// Debugger runs an assembled program on the CPU emulator, stopping at
+	// breakpoints and watchpoints, and maps ROM addresses back to the lines
+	// of the source.
+	Debugger struct {
+		CPU     *cpu.CPU
+		Symbols *asm.SymbolTable
+		Source  *asm.Source
+		Out     io.Writer
+
+		// lines holds the source line of each ROM address.
+		lines       []int
+		breakpoints map[uint16]bool
+		// watchpoints holds the last seen value of each watched RAM word.
+		watchpoints map[int]int16
+	}
+)
+
+// New loads prog, parsed from src, into a fresh CPU.
+func New(prog asm.Program, src *asm.Source) (dbg *Debugger, err error) {
+	if len(src.InstrLines) != len(prog) {
+		return nil, asm.ErrSourceMismatch
+	}
+
+	dbg = &Debugger{
+		CPU:         cpu.New(),
+		Source:      src,
+		Out:         io.Discard,
+		breakpoints: map[uint16]bool{},
+		watchpoints: map[int]int16{},
+	}
+	if err = dbg.CPU.Load(prog); err != nil {
+		return nil, err
+	}
+	_, dbg.Symbols = prog.ResolveSymbols()
+
+	for idx, instr := range prog {
+		if _, ok := instr.(*asm.LabelInstruction); !ok {
+			dbg.lines = append(dbg.lines, src.InstrLines[idx])
+		}
+	}
+	return
+}
+
+// Run reads commands from r until quit or the end of the input. An empty line
+// repeats the previous command.
+func (dbg *Debugger) Run(r io.Reader) (err error) {
+	var last string
+	s := bufio.NewScanner(r)
+	for {
+		fmt.Fprint(dbg.Out, Prompt)
+		if !s.Scan() {
+			fmt.Fprintln(dbg.Out)
+			return s.Err()
+		}
+
+		line := strings.TrimSpace(s.Text())
+		if line == "" {
+			line = last
+		}
+		last = line
+
+		quit, err := dbg.Exec(line)
+		if err != nil {
+			fmt.Fprintln(dbg.Out, "error:", err)
+		} else if quit {
+			return nil
+		}
+	}
+}
+
+// Exec executes a single command line, and reports whether it is quit.
+func (dbg *Debugger) Exec(line string) (quit bool, err error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return
+	}
+	cmd, args := fields[0], fields[1:]
+
+	switch cmd {
+	case "break", "b":
+		var pc uint16
+		if pc, err = dbg.argROM(cmd, args); err == nil {
+			dbg.breakpoints[pc] = true
+			dbg.printf("breakpoint at %s\n", dbg.Location(pc))
+		}
+	case "delete", "d":
+		if len(args) == 0 {
+			clear(dbg.breakpoints)
+			return
+		}
+		var pc uint16
+		if pc, err = dbg.argROM(cmd, args); err == nil {
+			delete(dbg.breakpoints, pc)
+		}
+	case "watch", "w":
+		var address int
+		if address, err = dbg.argRAM(cmd, args); err == nil {
+			dbg.watchpoints[address] = dbg.CPU.RAM[address]
+			dbg.printf("watchpoint on %s\n", dbg.word(address))
+		}
+	case "unwatch":
+		if len(args) == 0 {
+			clear(dbg.watchpoints)
+			return
+		}
+		var address int
+		if address, err = dbg.argRAM(cmd, args); err == nil {
+			delete(dbg.watchpoints, address)
+		}
+	case "info", "i":
+		dbg.info()
+	case "step", "s":
+		var n int
+		if n, err = dbg.argCount(cmd, args, 1); err == nil {
+			dbg.run(n)
+		}
+	case "continue", "c":
+		var n int
+		if n, err = dbg.argCount(cmd, args, DefaultLimit); err == nil {
+			dbg.run(n)
+		}
+	case "regs", "r":
+		dbg.printf("A=%d D=%d PC=%d M=%d cycles=%d\n",
+			dbg.CPU.A, dbg.CPU.D, dbg.CPU.PC, dbg.CPU.RAM[uint16(dbg.CPU.A)%cpu.RAMSize], dbg.CPU.Cycles)
+	case "print", "p":
+		var address int
+		if address, err = dbg.argRAM(cmd, args); err == nil {
+			dbg.printf("%s\n", dbg.word(address))
+		}
+	case "x":
+		var address, n int
+		if address, err = dbg.argRAM(cmd, args); err != nil {
+			return
+		}
+		if n, err = dbg.argCount(cmd, args[1:], 1); err != nil {
+			return
+		}
+		for i := range min(address+n, cpu.RAMSize) - address {
+			dbg.printf("%s\n", dbg.word(address+i))
+		}
+	case "set":
+		err = dbg.set(cmd, args)
+	case "list", "l":
+		dbg.list()
+	case "reset":
+		dbg.CPU.Reset()
+		dbg.printf("%s\n", dbg.Location(dbg.CPU.PC))
+	case "help", "h":
+		dbg.printf("%s\n", help)
+	case "quit", "q":
+		return true, nil
+	default:
+		err = ErrCommandUnknown{cmd: cmd}
+	}
+	return
+}
+
+func (dbg *Debugger) printf(format string, args ...any) {
+	fmt.Fprintf(dbg.Out, format, args...)
+}
+
+// run executes at most n instructions, stopping early before a breakpoint,
+// after a watched word changes, or at the final infinite loop of the program.
+func (dbg *Debugger) run(n int) {
+	for i := range n {
+		pc := dbg.CPU.PC
+		if i > 0 && dbg.breakpoints[pc] {
+			dbg.printf("breakpoint at %s\n", dbg.Location(pc))
+			return
+		}
+		if dbg.Halted() {
+			dbg.printf("program ended at %s\n", dbg.Location(pc))
+			return
+		}
+
+		dbg.CPU.Step()
+
+		changed := false
+		for _, address := range slices.Sorted(maps.Keys(dbg.watchpoints)) {
+			if old := dbg.watchpoints[address]; old != dbg.CPU.RAM[address] {
+				dbg.printf("watchpoint %s (was %d) after %s\n", dbg.word(address), old, dbg.Location(pc))
+				dbg.watchpoints[address] = dbg.CPU.RAM[address]
+				changed = true
+			}
+		}
+		if changed {
+			return
+		}
+	}
+	dbg.printf("%s\n", dbg.Location(dbg.CPU.PC))
+}
+
+// Halted reports whether PC is at the jump of an infinite loop of the form
+// (END) @END 0;JMP, which Hack programs end with.
+func (dbg *Debugger) Halted() bool {
+	pc := dbg.CPU.PC
+	return pc > 0 && int(pc) < cpu.ROMSize && dbg.CPU.ROM[pc] == haltJump && dbg.CPU.ROM[pc-1] == pc-1
+}
+
+// Location describes a ROM address: the address, the closest label before it
+// and the source line of its instruction.
+func (dbg *Debugger) Location(pc uint16) string {
+	builder := strings.Builder{}
+	builder.WriteString(strconv.Itoa(int(pc)))
+
+	label, offset := "", -1
+	for symbol, address := range dbg.Symbols.Labels {
+		diff := int(pc) - int(address)
+		if diff >= 0 && (offset < 0 || diff < offset || diff == offset && symbol < label) {
+			label, offset = symbol, diff
+		}
+	}
+	if offset == 0 {
+		fmt.Fprintf(&builder, " <%s>", label)
+	} else if offset > 0 {
+		fmt.Fprintf(&builder, " <%s+%d>", label, offset)
+	}
+
+	if int(pc) < len(dbg.lines) {
+		line := dbg.lines[pc]
+		fmt.Fprintf(&builder, " %s:%d: %s", dbg.Source.File, line, strings.TrimSpace(dbg.Source.Lines[line-1]))
+	}
+	return builder.String()
+}
+
+// word describes a RAM word, with the name of the variable it holds if any.
+func (dbg *Debugger) word(address int) string {
+	var names []string
+	for symbol, a := range dbg.Symbols.Variables {
+		if int(a) == address {
+			names = append(names, symbol)
+		}
+	}
+	if len(names) == 0 {
+		return fmt.Sprintf("RAM[%d] = %d", address, dbg.CPU.RAM[address])
+	}
+	slices.Sort(names)
+	return fmt.Sprintf("RAM[%d] (%s) = %d", address, strings.Join(names, ", "), dbg.CPU.RAM[address])
+}
+
+func (dbg *Debugger) info() {
+	for _, pc := range slices.Sorted(maps.Keys(dbg.breakpoints)) {
+		dbg.printf("breakpoint at %s\n", dbg.Location(pc))
+	}
+	for _, address := range slices.Sorted(maps.Keys(dbg.watchpoints)) {
+		dbg.printf("watchpoint on %s\n", dbg.word(address))
+	}
+}
+
+func (dbg *Debugger) list() {
+	pc := dbg.CPU.PC
+	if int(pc) >= len(dbg.lines) {
+		dbg.printf("%s\n", dbg.Location(pc))
+		return
+	}
+
+	current := dbg.lines[pc]
+	marks := map[int]string{current: "=>"}
+	for bp := range dbg.breakpoints {
+		if int(bp) < len(dbg.lines) && dbg.lines[bp] != current {
+			marks[dbg.lines[bp]] = " *"
+		}
+	}
+
+	from, to := max(current-listContext, 1), min(current+listContext, len(dbg.Source.Lines))
+	for line := from; line <= to; line++ {
+		dbg.printf("%2s %5d  %s\n", marks[line], line, dbg.Source.Lines[line-1])
+	}
+}
+
+func (dbg *Debugger) set(cmd string, args []string) (err error) {
+	if len(args) != 2 {
+		return ErrArgumentInvalid{cmd: cmd, arg: strings.Join(args, " ")}
+	}
+	value, err := strconv.ParseInt(args[1], 0, 32)
+	if err != nil || value < -32768 || value > 65535 {
+		return ErrArgumentInvalid{cmd: cmd, arg: args[1]}
+	}
+
+	switch args[0] {
+	case "A":
+		dbg.CPU.A = int16(value)
+	case "D":
+		dbg.CPU.D = int16(value)
+	case "PC":
+		dbg.CPU.PC = uint16(value)
+	default:
+		var address int
+		if address, err = dbg.argRAM(cmd, args[:1]); err != nil {
+			return
+		}
+		dbg.CPU.RAM[address] = int16(value)
+		if _, ok := dbg.watchpoints[address]; ok {
+			dbg.watchpoints[address] = int16(value)
+		}
+	}
+	return
+}
+
+// argROM resolves the first argument to a ROM address, given as a number or
+// a label.
+func (dbg *Debugger) argROM(cmd string, args []string) (pc uint16, err error) {
+	if len(args) == 0 {
+		return 0, ErrArgumentInvalid{cmd: cmd}
+	}
+	if address, ok := dbg.Symbols.Labels[args[0]]; ok {
+		return uint16(address), nil
+	}
+	address, err := dbg.number(cmd, args[0], cpu.ROMSize)
+	return uint16(address), err
+}
+
+// argRAM resolves the first argument to a RAM address, given as a number, a
+// predefined symbol or a variable.
+func (dbg *Debugger) argRAM(cmd string, args []string) (address int, err error) {
+	if len(args) == 0 {
+		return 0, ErrArgumentInvalid{cmd: cmd}
+	}
+	if a, ok := dbg.Symbols.Predefined[args[0]]; ok {
+		return int(a), nil
+	}
+	if a, ok := dbg.Symbols.Variables[args[0]]; ok {
+		return int(a), nil
+	}
+	return dbg.number(cmd, args[0], cpu.RAMSize)
+}
+
+// argCount parses the optional count argument of a command.
+func (dbg *Debugger) argCount(cmd string, args []string, def int) (n int, err error) {
+	if len(args) == 0 {
+		return def, nil
+	}
+	if n, err = strconv.Atoi(args[0]); err != nil || n < 1 {
+		return 0, ErrArgumentInvalid{cmd: cmd, arg: args[0]}
+	}
+	return
+}
+
+func (dbg *Debugger) number(cmd, arg string, limit int) (n int, err error) {
+	value, err := strconv.ParseInt(arg, 0, 32)
+	if err != nil {
+		if arg != "" && !strings.ContainsAny(arg[:1], "0123456789-") {
+			return 0, ErrSymbolUndefined{symbol: arg}
+		}
+		return 0, ErrArgumentInvalid{cmd: cmd, arg: arg}
+	}
+	if value < 0 || value >= int64(limit) {
+		return 0, ErrAddressInvalid{address: int(value)}
+	}
+	return int(value), nil
+}
@@ -0,0 +1,140 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debug
+
+import (
+	"bytes"
+	"hack/internal/asm"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const sum = `// Adds 1 to n into sum.
+@sum
+M=0
+@i
+M=1
+(LOOP)
+@i
+D=M
+@R0
+D=D-M
+@END
+D;JGT
+@i
+D=M
+@sum
+M=D+M
+@i
+M=M+1
+@LOOP
+0;JMP
+(END)
+@END
+0;JMP
+`
+
+func load(t *testing.T) (*Debugger, *bytes.Buffer) {
+	prog, src, err := asm.ParseSource("Sum.asm", strings.NewReader(sum), 0)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	dbg, err := New(prog, src)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	out := &bytes.Buffer{}
+	dbg.Out = out
+	return dbg, out
+}
+
+func exec(t *testing.T, dbg *Debugger, out *bytes.Buffer, line string) string {
+	out.Reset()
+	_, err := dbg.Exec(line)
+	assert.Nil(t, err, line)
+	return out.String()
+}
+
+func TestDebuggerBreakpoint(t *testing.T) {
+	dbg, out := load(t)
+	dbg.CPU.RAM[0] = 3
+
+	assert.Equal(t, "breakpoint at 4 <LOOP> Sum.asm:7: @i\n", exec(t, dbg, out, "break LOOP"))
+	assert.Equal(t, "breakpoint at 4 <LOOP> Sum.asm:7: @i\n", exec(t, dbg, out, "continue"))
+	assert.Equal(t, "breakpoint at 4 <LOOP> Sum.asm:7: @i\n", exec(t, dbg, out, "continue"))
+	assert.Equal(t, "RAM[16] (sum) = 1\n", exec(t, dbg, out, "print sum"))
+
+	exec(t, dbg, out, "delete LOOP")
+	assert.Equal(t, "program ended at 19 <END+1> Sum.asm:23: 0;JMP\n", exec(t, dbg, out, "continue"))
+	assert.Equal(t, "RAM[16] (sum) = 6\nRAM[17] (i) = 4\n", exec(t, dbg, out, "x 16 2"))
+}
+
+func TestDebuggerWatchpoint(t *testing.T) {
+	dbg, out := load(t)
+	dbg.CPU.RAM[0] = 2
+	dbg.CPU.RAM[16] = -1
+
+	assert.Equal(t, "watchpoint on RAM[16] (sum) = -1\n", exec(t, dbg, out, "watch sum"))
+	assert.Equal(t, "watchpoint RAM[16] (sum) = 0 (was -1) after 1 Sum.asm:3: M=0\n", exec(t, dbg, out, "continue"))
+	assert.Equal(t, "watchpoint RAM[16] (sum) = 1 (was 0) after 13 <LOOP+9> Sum.asm:16: M=D+M\n", exec(t, dbg, out, "continue"))
+	assert.Equal(t, "watchpoint on RAM[16] (sum) = 1\n", exec(t, dbg, out, "info"))
+
+	assert.Equal(t, "17 <LOOP+13> Sum.asm:20: 0;JMP\n", exec(t, dbg, out, "step 3"))
+	assert.Equal(t, "A=4 D=1 PC=17 M=0 cycles=17\n", exec(t, dbg, out, "regs"))
+}
+
+func TestDebuggerList(t *testing.T) {
+	dbg, out := load(t)
+	exec(t, dbg, out, "break 7")
+	exec(t, dbg, out, "step 5")
+
+	assert.Equal(t, `       3  M=0
+       4  @i
+       5  M=1
+       6  (LOOP)
+       7  @i
+=>     8  D=M
+       9  @R0
+ *    10  D=D-M
+      11  @END
+      12  D;JGT
+      13  @i
+`, exec(t, dbg, out, "list"))
+}
+
+func TestDebuggerSet(t *testing.T) {
+	dbg, out := load(t)
+	exec(t, dbg, out, "set D -5")
+	exec(t, dbg, out, "set R0 0x10")
+	exec(t, dbg, out, "set PC 4")
+	assert.Equal(t, int16(-5), dbg.CPU.D)
+	assert.Equal(t, int16(16), dbg.CPU.RAM[0])
+	assert.Equal(t, uint16(4), dbg.CPU.PC)
+}
+
+func TestDebuggerErrors(t *testing.T) {
+	dbg, _ := load(t)
+	for line, msg := range map[string]string{
+		"frobnicate":  "unknown command: frobnicate (try help)",
+		"break":       "missing argument to break",
+		"break NOPE":  "undefined symbol: NOPE",
+		"watch 40000": "address out of range: 40000",
+		"step -1":     "invalid argument to step: -1",
+		"set A":       "invalid argument to set: A",
+	} {
+		_, err := dbg.Exec(line)
+		if assert.NotNil(t, err, line) {
+			assert.Equal(t, msg, err.Error(), line)
+		}
+	}
+}
+
+func TestDebuggerRun(t *testing.T) {
+	dbg, out := load(t)
+	assert.Nil(t, dbg.Run(strings.NewReader("step\n\nbogus\nquit\nstep\n")))
+	assert.Equal(t, "(hack) 1 Sum.asm:3: M=0\n(hack) 2 Sum.asm:4: @i\n(hack) error: unknown command: bogus (try help)\n(hack) ", out.String())
+}
@@ -0,0 +1,45 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debug
+
+import "strconv"
+
+type (
+	ErrCommandUnknown struct {
+		cmd string
+	}
+
+	ErrArgumentInvalid struct {
+		cmd string
+		arg string
+	}
+
+	ErrSymbolUndefined struct {
+		symbol string
+	}
+
+	ErrAddressInvalid struct {
+		address int
+	}
+)
+
+func (err ErrCommandUnknown) Error() string {
+	return "unknown command: " + err.cmd + " (try help)"
+}
+
+func (err ErrArgumentInvalid) Error() string {
+	if err.arg == "" {
+		return "missing argument to " + err.cmd
+	}
+	return "invalid argument to " + err.cmd + ": " + err.arg
+}
+
+func (err ErrSymbolUndefined) Error() string {
+	return "undefined symbol: " + err.symbol
+}
+
+func (err ErrAddressInvalid) Error() string {
+	return "address out of range: " + strconv.Itoa(err.address)
+}
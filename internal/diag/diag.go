@@ -0,0 +1,101 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diag
+
+import (
+	"io"
+	"strconv"
+	"strings"
+)
+
+type (
+	// Diagnostic is an error located at a position of a source file.
+	Diagnostic struct {
+		File   string
+		Line   int
+		Column int
+		Source string
+		Err    error
+	}
+
+	// List is a list of diagnostics. It unwraps to its elements, like the
+	// error returned by errors.Join.
+	List []Diagnostic
+)
+
+func (d Diagnostic) Error() string {
+	builder := strings.Builder{}
+	if d.File != "" {
+		builder.WriteString(d.File)
+		builder.WriteByte(':')
+	}
+	builder.WriteString(strconv.Itoa(d.Line))
+	builder.WriteByte(':')
+	builder.WriteString(strconv.Itoa(d.Column))
+	builder.WriteString(": ")
+	builder.WriteString(d.Err.Error())
+	return builder.String()
+}
+
+func (d Diagnostic) Unwrap() error {
+	return d.Err
+}
+
+// Format writes the diagnostic followed by its source line and a caret under
+// the offending column.
+func (d Diagnostic) Format(w io.Writer) (err error) {
+	if _, err = w.Write([]byte(d.Error())); err != nil {
+		return
+	}
+	if d.Source == "" {
+		_, err = w.Write([]byte{'\n'})
+		return
+	}
+
+	var pad []byte
+	for _, char := range d.Source[:min(max(d.Column-1, 0), len(d.Source))] {
+		if char == '\t' {
+			pad = append(pad, '\t')
+		} else {
+			pad = append(pad, ' ')
+		}
+	}
+
+	if _, err = w.Write([]byte("\n\t" + d.Source + "\n\t")); err != nil {
+		return
+	}
+	if _, err = w.Write(append(pad, '^', '\n')); err != nil {
+		return
+	}
+
+	return
+}
+
+func (l List) Error() string {
+	builder := strings.Builder{}
+	for idx, d := range l {
+		if idx > 0 {
+			builder.WriteByte('\n')
+		}
+		builder.WriteString(d.Error())
+	}
+	return builder.String()
+}
+
+func (l List) Unwrap() []error {
+	errs := make([]error, len(l))
+	for idx, d := range l {
+		errs[idx] = d
+	}
+	return errs
+}
+
+// Err returns l as an error, or nil if l is empty.
+func (l List) Err() error {
+	if len(l) == 0 {
+		return nil
+	}
+	return l
+}
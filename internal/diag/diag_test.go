@@ -0,0 +1,49 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package diag
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiagnosticError(t *testing.T) {
+	t.Run("file", func(t *testing.T) {
+		d := Diagnostic{File: "Max.asm", Line: 3, Column: 5, Err: errors.New("invalid comp: X")}
+		assert.Equal(t, "Max.asm:3:5: invalid comp: X", d.Error())
+	})
+
+	t.Run("no file", func(t *testing.T) {
+		d := Diagnostic{Line: 3, Column: 5, Err: errors.New("invalid comp: X")}
+		assert.Equal(t, "3:5: invalid comp: X", d.Error())
+	})
+}
+
+func TestDiagnosticFormat(t *testing.T) {
+	d := Diagnostic{File: "Max.asm", Line: 3, Column: 3, Source: "\tD=X", Err: errors.New("invalid comp: X")}
+
+	builder := strings.Builder{}
+	assert.Nil(t, d.Format(&builder))
+	assert.Equal(t, "Max.asm:3:3: invalid comp: X\n\t\tD=X\n\t\t ^\n", builder.String())
+}
+
+func TestList(t *testing.T) {
+	var l List
+	assert.Nil(t, l.Err())
+
+	l = append(l,
+		Diagnostic{Line: 1, Column: 1, Err: errors.New("a")},
+		Diagnostic{Line: 2, Column: 3, Err: errors.New("b")},
+	)
+	err := l.Err()
+	assert.Equal(t, "1:1: a\n2:3: b", err.Error())
+
+	var d Diagnostic
+	assert.True(t, errors.As(err, &d))
+	assert.Equal(t, l[0], d)
+}
@@ -0,0 +1,140 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package difftest checks the VM translator against the VM emulator: a
+// program runs once in the emulator and once translated, assembled and
+// executed on the CPU, and the final RAM of both runs must agree.
+package difftest
+
+import (
+	"fmt"
+	"hack/internal/asm"
+	"hack/internal/cpu"
+	"hack/internal/vm"
+	"hack/internal/vme"
+)
+
+const (
+	// File is the name the compared programs are loaded under.
+	File = "Main"
+
+	MaxSteps  = 100_000
+	MaxCycles = 10_000_000
+
+	haltLabel = "$HALT"
+)
+
+type (
+	Mismatch struct {
+		Address    int
+		Emulated   int16
+		Translated int16
+	}
+
+	// RAM is the memory left by a run.
+	RAM [vme.RAMSize]int16
+)
+
+func (mismatch Mismatch) String() string {
+	return fmt.Sprintf("RAM[%d]: emulated %d, translated %d", mismatch.Address, mismatch.Emulated, mismatch.Translated)
+}
+
+// Setup initializes the memory both runs start from.
+func Setup(ram []int16) {
+	ram[vme.SP] = 256
+	ram[vme.LCL] = 300
+	ram[vme.ARG] = 400
+	ram[vme.THIS] = 3000
+	ram[vme.THAT] = 4000
+}
+
+// Compare runs prog, which must define Main.main, both ways and returns an
+// ErrMismatch listing the addresses whose final values differ. The
+// translation is optimized with rules, if any, before running.
+func Compare(prog vm.Program, rules ...asm.Rule) (err error) {
+	var emulated, translated RAM
+	if emulated, err = Emulate(prog); err != nil {
+		return
+	}
+	if translated, err = Translate(prog, rules...); err != nil {
+		return
+	}
+
+	if mismatches := Diff(&emulated, &translated); len(mismatches) > 0 {
+		return ErrMismatch{mismatches: mismatches}
+	}
+	return
+}
+
+// Emulate runs prog in the VM emulator until Main.main returns.
+func Emulate(prog vm.Program) (ram RAM, err error) {
+	m := vme.New()
+	m.Load(File, prog)
+	if err = m.Link(); err != nil {
+		return
+	}
+	Setup(m.RAM[:])
+
+	if err = m.Run(MaxSteps); err != nil {
+		return
+	}
+	if !m.Halted {
+		return ram, ErrTimeout{engine: "VM emulator"}
+	}
+	return m.RAM, nil
+}
+
+// Translate translates prog after a call to Main.main, optimizes it with
+// rules, assembles it and runs it on the CPU until Main.main returns.
+func Translate(prog vm.Program, rules ...asm.Rule) (ram RAM, err error) {
+	t := &vm.Translator{}
+
+	var instrs, body asm.Program
+	if instrs, err = (vm.Statement{Command: vm.CommandCall, Function: vme.FallbackFunction}).Instructions(t); err != nil {
+		return
+	}
+	instrs = append(instrs,
+		&asm.LabelInstruction{Symbol: haltLabel},
+		&asm.AddressInstructionSymbol{Symbol: haltLabel},
+		&asm.ComputeInstruction{Comp: asm.Comp00, Jump: asm.JumpJMP},
+	)
+	t.SetFile(File)
+	if body, err = prog.Instructions(t); err != nil {
+		return
+	}
+	instrs = append(instrs, body...)
+	if len(rules) > 0 {
+		if instrs, err = instrs.Optimize(rules...); err != nil {
+			return
+		}
+	}
+
+	c := cpu.New()
+	if err = c.Load(instrs); err != nil {
+		return
+	}
+	Setup(c.RAM[:])
+
+	_, syms := instrs.ResolveSymbols()
+	halt := uint16(syms.Labels[haltLabel])
+	if !c.RunUntil(MaxCycles, func(c *cpu.CPU) bool { return c.PC == halt }) {
+		return ram, ErrTimeout{engine: "CPU"}
+	}
+	return c.RAM, nil
+}
+
+// Diff compares the observable state of two runs: everything but the
+// translator's scratch registers R13-R15 and the dead stack above SP.
+func Diff(emulated, translated *RAM) (mismatches []Mismatch) {
+	sp := int(emulated[vme.SP])
+	for addr := range emulated {
+		if addr >= 13 && addr < 16 || addr >= sp && addr < vme.HeapBase {
+			continue
+		}
+		if emulated[addr] != translated[addr] {
+			mismatches = append(mismatches, Mismatch{Address: addr, Emulated: emulated[addr], Translated: translated[addr]})
+		}
+	}
+	return
+}
@@ -0,0 +1,158 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package difftest
+
+import (
+	"hack/internal/asm"
+	"hack/internal/vm"
+	"hack/internal/vme"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompare(t *testing.T) {
+	prog, err := vm.ParseString(`
+function Main.main 1
+push constant 3
+push constant 4
+call Main.add 2
+pop local 0
+push local 0
+pop static 0
+push constant 1
+if-goto SKIP
+push constant 99
+pop static 1
+label SKIP
+push constant 0
+return
+
+function Main.add 0
+push argument 0
+push argument 1
+add
+return
+`)
+	assert.Nil(t, err)
+	assert.Nil(t, Compare(prog))
+	assert.Nil(t, Compare(prog, asm.Rules...))
+
+	ram, err := Emulate(prog)
+	assert.Nil(t, err)
+	assert.Equal(t, int16(7), ram[16])
+	assert.Equal(t, int16(0), ram[17])
+}
+
+func TestDiff(t *testing.T) {
+	var emulated, translated RAM
+	Setup(emulated[:])
+	Setup(translated[:])
+	translated[13] = 1
+	translated[300] = 1
+	translated[100] = 1
+
+	assert.Equal(t, []Mismatch{{Address: 100, Emulated: 0, Translated: 1}}, Diff(&emulated, &translated))
+}
+
+func FuzzCompare(f *testing.F) {
+	for seed := range uint64(32) {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, seed uint64) {
+		prog := Generate(rand.New(rand.NewPCG(seed, seed)))
+		if err := Compare(prog); err != nil {
+			str, _ := vm.FormatString(prog)
+			t.Fatalf("%v\n%s", err, str)
+		}
+	})
+}
+
+func TestOptimize(t *testing.T) {
+	prog, err := vm.ParseString(`
+function Main.main 0
+push static 0
+push constant 2
+call Math.multiply 2
+push constant 1
+neg
+push static 1
+call Math.multiply 2
+push static 0
+push constant 1
+call Math.divide 2
+add
+add
+pop static 2
+push static 2
+pop static 2
+push constant 0
+return
+`)
+	assert.Nil(t, err)
+	optimized, stats := prog.Optimize(vm.Rules...)
+	assert.Equal(t, 6, stats.Removed())
+
+	var rams [2]RAM
+	for i, p := range []vm.Program{prog, optimized} {
+		m := vme.New()
+		m.Load(File, p)
+		assert.Nil(t, m.Link())
+		Setup(m.RAM[:])
+		m.RAM[16], m.RAM[17] = 21, 5
+		assert.Nil(t, m.Run(MaxSteps))
+		rams[i] = m.RAM
+	}
+	assert.Equal(t, int16(42-5+21), rams[0][18])
+	assert.Empty(t, Diff(&rams[0], &rams[1]))
+}
+
+func FuzzOptimize(f *testing.F) {
+	for seed := range uint64(32) {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, seed uint64) {
+		prog := GenerateMath(rand.New(rand.NewPCG(seed, seed)))
+		optimized, _ := prog.Optimize(vm.Rules...)
+
+		var rams [2]RAM
+		for i, p := range []vm.Program{prog, optimized} {
+			var err error
+			if rams[i], err = Emulate(p); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if mismatches := Diff(&rams[0], &rams[1]); len(mismatches) > 0 {
+			str, _ := vm.FormatString(prog)
+			t.Fatalf("%v\n%s", ErrMismatch{mismatches: mismatches}, str)
+		}
+	})
+}
+
+func FuzzCompareOptimized(f *testing.F) {
+	for seed := range uint64(32) {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, seed uint64) {
+		prog := Generate(rand.New(rand.NewPCG(seed, seed)))
+		emulated, err := Emulate(prog)
+		if err != nil {
+			t.Fatal(err)
+		}
+		optimized, _ := prog.Optimize(vm.Rules...)
+		translated, err := Translate(optimized, asm.Rules...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mismatches := Diff(&emulated, &translated); len(mismatches) > 0 {
+			str, _ := vm.FormatString(prog)
+			t.Fatalf("%v\n%s", ErrMismatch{mismatches: mismatches}, str)
+		}
+	})
+}
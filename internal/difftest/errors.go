@@ -0,0 +1,27 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package difftest
+
+type (
+	ErrTimeout struct {
+		engine string
+	}
+
+	ErrMismatch struct {
+		mismatches []Mismatch
+	}
+)
+
+func (err ErrTimeout) Error() string {
+	return err.engine + " did not halt"
+}
+
+func (err ErrMismatch) Error() string {
+	str := "RAM mismatch:"
+	for _, mismatch := range err.mismatches {
+		str += " " + mismatch.String()
+	}
+	return str
+}
@@ -0,0 +1,229 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package difftest
+
+import (
+	"hack/internal/vm"
+	"math/rand/v2"
+	"strconv"
+)
+
+const (
+	maxFunctions  = 4
+	maxArgs       = 3
+	maxLocals     = 4
+	maxStatements = 40
+	maxBlockDepth = 2
+
+	// Indexes into this and that stay small so the segments set up by Setup
+	// do not overlap.
+	maxObjectIndex = 8
+	maxStaticIndex = 8
+)
+
+type (
+	function struct {
+		name   string
+		args   int16
+		locals int16
+	}
+
+	generator struct {
+		r         *rand.Rand
+		functions []function
+		prog      vm.Program
+		labels    int
+
+		// math enables calls to Math.multiply and Math.divide, which only
+		// the VM emulator implements.
+		math bool
+	}
+)
+
+// Generate returns a random program that always terminates and only touches
+// memory inside its segments: Main.main followed by helper functions, each
+// only calling the helpers defined after it.
+func Generate(r *rand.Rand) vm.Program {
+	return (&generator{r: r}).generate()
+}
+
+// GenerateMath is like Generate, but the program also calls Math.multiply and
+// Math.divide with a constant operand of 0, 1, -1 or 2, never dividing by 0.
+// It can only run in the VM emulator.
+func GenerateMath(r *rand.Rand) vm.Program {
+	return (&generator{r: r, math: true}).generate()
+}
+
+func (g *generator) generate() vm.Program {
+	g.functions = append(g.functions, function{name: "Main.main", locals: int16(g.r.IntN(maxLocals + 1))})
+	for i := range g.r.IntN(maxFunctions) {
+		g.functions = append(g.functions, function{
+			name:   "Main.f" + strconv.Itoa(i),
+			args:   int16(g.r.IntN(maxArgs + 1)),
+			locals: int16(g.r.IntN(maxLocals + 1)),
+		})
+	}
+
+	for i := range g.functions {
+		g.function(i)
+	}
+	return g.prog
+}
+
+func (g *generator) emit(stmt vm.Statement) {
+	g.prog = append(g.prog, stmt)
+}
+
+func (g *generator) label() string {
+	label := "L" + strconv.Itoa(g.labels)
+	g.labels += 1
+	return label
+}
+
+func (g *generator) function(i int) {
+	fn := g.functions[i]
+	g.emit(vm.Statement{Command: vm.CommandFunction, Function: fn.name, Count: fn.locals})
+
+	depth := g.block(i, 0, 0, 0, g.r.IntN(maxStatements))
+	if depth == 0 {
+		g.push(i)
+	}
+	g.emit(vm.Statement{Command: vm.CommandReturn})
+}
+
+// block emits n random statements starting at stack depth depth, never
+// popping below floor, and returns the resulting depth.
+func (g *generator) block(i, level, floor, depth, n int) int {
+	for range n {
+		switch choice := g.r.IntN(10); {
+		case choice < 3 || depth == floor:
+			g.push(i)
+			depth += 1
+		case choice < 5:
+			g.pop(i)
+			depth -= 1
+		case choice < 7 && depth >= floor+2:
+			g.emit(vm.Statement{Command: []vm.Command{
+				vm.CommandAdd, vm.CommandSub, vm.CommandAnd, vm.CommandOr,
+				vm.CommandEq, vm.CommandGt, vm.CommandLt,
+			}[g.r.IntN(7)]})
+			depth -= 1
+		case choice < 8:
+			g.emit(vm.Statement{Command: []vm.Command{vm.CommandNeg, vm.CommandNot}[g.r.IntN(2)]})
+		case choice < 9 && g.math && g.r.IntN(2) == 0:
+			depth = g.mathCall(i, floor, depth)
+		case choice < 9 && i+1 < len(g.functions):
+			callee := g.functions[i+1+g.r.IntN(len(g.functions)-i-1)]
+			for ; depth < floor+int(callee.args); depth++ {
+				g.push(i)
+			}
+			g.emit(vm.Statement{Command: vm.CommandCall, Function: callee.name, Count: callee.args})
+			depth += 1 - int(callee.args)
+		case level < maxBlockDepth:
+			// A forward jump over a block that leaves the stack as it found it.
+			label := g.label()
+			if g.r.IntN(2) == 0 {
+				g.emit(vm.Statement{Command: vm.CommandIfGoto, Label: label})
+				depth -= 1
+			} else {
+				g.emit(vm.Statement{Command: vm.CommandGoto, Label: label})
+			}
+			for inner := g.block(i, level+1, depth, depth, g.r.IntN(maxStatements/4)); inner > depth; inner-- {
+				g.pop(i)
+			}
+			g.emit(vm.Statement{Command: vm.CommandLabel, Label: label})
+		default:
+			g.push(i)
+			depth += 1
+		}
+	}
+
+	return depth
+}
+
+// mathCall emits a call to Math.multiply or Math.divide with a constant
+// operand, the other one being either pushed or already on the stack, and
+// returns the resulting depth.
+func (g *generator) mathCall(i, floor, depth int) int {
+	function, k := vm.FunctionMultiply, []int16{0, 1, -1, 2}[g.r.IntN(4)]
+	if g.r.IntN(2) == 0 {
+		function, k = vm.FunctionDivide, []int16{1, -1, 2}[g.r.IntN(3)]
+	}
+
+	switch {
+	case depth > floor && g.r.IntN(2) == 0:
+		g.constant(k)
+	case function == vm.FunctionMultiply && g.r.IntN(2) == 0:
+		g.constant(k)
+		g.push(i)
+		depth += 1
+	default:
+		g.push(i)
+		g.constant(k)
+		depth += 1
+	}
+	g.emit(vm.Statement{Command: vm.CommandCall, Function: function, Count: 2})
+
+	return depth
+}
+
+// constant pushes k, negating its absolute value if k is negative.
+func (g *generator) constant(k int16) {
+	g.emit(vm.Statement{Command: vm.CommandPush, Segment: vm.SegmentConstant, Index: max(k, -k)})
+	if k < 0 {
+		g.emit(vm.Statement{Command: vm.CommandNeg})
+	}
+}
+
+func (g *generator) push(i int) {
+	fn := g.functions[i]
+
+	stmt := vm.Statement{Command: vm.CommandPush}
+	switch choice := g.r.IntN(8); {
+	case choice < 3:
+		stmt.Segment = vm.SegmentConstant
+		if g.r.IntN(4) == 0 {
+			stmt.Index = int16(g.r.IntN(1 << 15))
+		} else {
+			stmt.Index = int16(g.r.IntN(16))
+		}
+	case choice == 3:
+		stmt.Segment = vm.SegmentPointer
+		stmt.Index = int16(g.r.IntN(vm.PointerSize))
+	default:
+		stmt = g.location(fn, vm.CommandPush)
+	}
+	g.emit(stmt)
+}
+
+func (g *generator) pop(i int) {
+	g.emit(g.location(g.functions[i], vm.CommandPop))
+}
+
+// location picks a writable segment entry of fn.
+func (g *generator) location(fn function, cmd vm.Command) vm.Statement {
+	segments := []vm.Segment{vm.SegmentThis, vm.SegmentThat, vm.SegmentTemp, vm.SegmentStatic}
+	if fn.args > 0 {
+		segments = append(segments, vm.SegmentArgument)
+	}
+	if fn.locals > 0 {
+		segments = append(segments, vm.SegmentLocal)
+	}
+
+	stmt := vm.Statement{Command: cmd, Segment: segments[g.r.IntN(len(segments))]}
+	switch stmt.Segment {
+	case vm.SegmentThis, vm.SegmentThat:
+		stmt.Index = int16(g.r.IntN(maxObjectIndex))
+	case vm.SegmentTemp:
+		stmt.Index = int16(g.r.IntN(vm.TempSize))
+	case vm.SegmentStatic:
+		stmt.Index = int16(g.r.IntN(maxStaticIndex))
+	case vm.SegmentArgument:
+		stmt.Index = int16(g.r.IntN(int(fn.args)))
+	case vm.SegmentLocal:
+		stmt.Index = int16(g.r.IntN(int(fn.locals)))
+	}
+	return stmt
+}
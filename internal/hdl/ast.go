@@ -0,0 +1,83 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hdl
+
+type (
+	// Chip is the interface and implementation of a chip. Builtin chips name
+	// their Go implementation instead of listing parts.
+	Chip struct {
+		Name    string
+		File    string
+		Inputs  []Pin
+		Outputs []Pin
+		Parts   []Part
+
+		Builtin string
+		// Clocked lists the inputs a builtin chip only reads on the clock,
+		// which its outputs therefore do not depend on combinationally.
+		Clocked []string
+	}
+
+	Pin struct {
+		Name  string
+		Width int
+	}
+
+	Part struct {
+		Name  string
+		Conns []Connection
+
+		Line   int
+		Column int
+	}
+
+	// Connection connects bits of a part pin to bits of a chip signal, an
+	// internal signal or a constant.
+	Connection struct {
+		Pin    Bus
+		Signal Bus
+	}
+
+	// Bus is a signal name, restricted to the bits Lo to Hi if Ranged.
+	Bus struct {
+		Name   string
+		Ranged bool
+		Lo, Hi int
+	}
+)
+
+// Pin returns the input or output pin named name.
+func (chip *Chip) Pin(name string) (pin Pin, ok bool) {
+	for _, pin = range chip.Inputs {
+		if pin.Name == name {
+			return pin, true
+		}
+	}
+	for _, pin = range chip.Outputs {
+		if pin.Name == name {
+			return pin, true
+		}
+	}
+	return
+}
+
+// IsInput reports whether name is an input pin of the chip.
+func (chip *Chip) IsInput(name string) bool {
+	for _, pin := range chip.Inputs {
+		if pin.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Width returns the number of bits of the bus, given the width of the whole
+// signal.
+func (bus Bus) Width(full int) int {
+	if bus.Ranged {
+		return bus.Hi - bus.Lo + 1
+	}
+	return full
+}
@@ -0,0 +1,168 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hdl
+
+import (
+	"slices"
+)
+
+type (
+	// part is a primitive of the flattened circuit, reading and writing the
+	// wires it is connected to.
+	part interface {
+		// inputs returns the wires eval reads, and outputs the wires it
+		// writes.
+		inputs() []int
+		outputs() []int
+		eval(w []bool)
+		// remap replaces each wire by its representative once the circuit
+		// is connected.
+		remap(find func(int) int)
+	}
+
+	// clockedPart is a part with state, updated on the clock.
+	clockedPart interface {
+		part
+		// tick samples the inputs and tock makes the sample the new state.
+		tick(w []bool)
+		tock()
+		// emit writes the outputs depending only on the state. It runs
+		// before the combinational parts are evaluated, so that these
+		// outputs never close a loop.
+		emit(w []bool)
+	}
+
+	// memoryPart is a part whose state scripts can read and write, as in
+	// RAM16K[3] or ARegister[].
+	memoryPart interface {
+		memories() map[string][]int16
+	}
+
+	// Builtin is a chip implemented in Go. New creates the part from the
+	// wires of each pin, least significant bit first.
+	Builtin struct {
+		Chip *Chip
+		New  func(pins map[string][]int) part
+	}
+
+	// pins gives a builtin part access to the wires of its pins. Its inputs
+	// are those not listed as clocked by the chip.
+	pins struct {
+		chip  *Chip
+		wires map[string][]int
+	}
+
+	nand struct {
+		a, b, out int
+	}
+
+	dff struct {
+		in, out     int
+		state, next bool
+	}
+)
+
+// Builtins maps chip names to their Go implementations.
+var Builtins = map[string]Builtin{}
+
+// define adds the builtin chip whose interface is declared by src.
+func define(src string, newPart func(p pins) part) {
+	chip, err := ParseString(src)
+	if err != nil {
+		panic(err)
+	}
+	Builtins[chip.Name] = Builtin{
+		Chip: chip,
+		New: func(wires map[string][]int) part {
+			return newPart(pins{chip: chip, wires: wires})
+		},
+	}
+}
+
+func init() {
+	define(`CHIP Nand { IN a, b; OUT out; BUILTIN Nand; }`, func(p pins) part {
+		return &nand{a: p.wires["a"][0], b: p.wires["b"][0], out: p.wires["out"][0]}
+	})
+	define(`CHIP DFF { IN in; OUT out; BUILTIN DFF; CLOCKED in; }`, func(p pins) part {
+		return &dff{in: p.wires["in"][0], out: p.wires["out"][0]}
+	})
+}
+
+func (p pins) inputs() (wires []int) {
+	for _, pin := range p.chip.Inputs {
+		if !slices.Contains(p.chip.Clocked, pin.Name) {
+			wires = append(wires, p.wires[pin.Name]...)
+		}
+	}
+	return
+}
+
+func (p pins) outputs() (wires []int) {
+	for _, pin := range p.chip.Outputs {
+		wires = append(wires, p.wires[pin.Name]...)
+	}
+	return
+}
+
+func (p pins) remap(find func(int) int) {
+	for _, wires := range p.wires {
+		for i, wire := range wires {
+			wires[i] = find(wire)
+		}
+	}
+}
+
+// has reports whether the chip has a pin named name.
+func (p pins) has(name string) bool {
+	return p.wires[name] != nil
+}
+
+// get returns the unsigned value of a pin.
+func (p pins) get(w []bool, name string) (value int) {
+	for i, wire := range p.wires[name] {
+		if w[wire] {
+			value |= 1 << i
+		}
+	}
+	return
+}
+
+// set sets a pin to value, truncated to its width.
+func (p pins) set(w []bool, name string, value int) {
+	for i, wire := range p.wires[name] {
+		w[wire] = value&(1<<i) != 0
+	}
+}
+
+func (gate *nand) inputs() []int  { return []int{gate.a, gate.b} }
+func (gate *nand) outputs() []int { return []int{gate.out} }
+
+func (gate *nand) eval(w []bool) {
+	w[gate.out] = !(w[gate.a] && w[gate.b])
+}
+
+func (gate *nand) remap(find func(int) int) {
+	gate.a, gate.b, gate.out = find(gate.a), find(gate.b), find(gate.out)
+}
+
+func (gate *dff) inputs() []int  { return nil }
+func (gate *dff) outputs() []int { return nil }
+func (gate *dff) eval(w []bool)  {}
+
+func (gate *dff) remap(find func(int) int) {
+	gate.in, gate.out = find(gate.in), find(gate.out)
+}
+
+func (gate *dff) tick(w []bool) {
+	gate.next = w[gate.in]
+}
+
+func (gate *dff) tock() {
+	gate.state = gate.next
+}
+
+func (gate *dff) emit(w []bool) {
+	w[gate.out] = gate.state
+}
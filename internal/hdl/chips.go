@@ -0,0 +1,333 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hdl
+
+import (
+	"hack/internal/cpu"
+	"strconv"
+)
+
+type (
+	// logic is a combinational builtin chip.
+	logic struct {
+		pins
+		fn func(p pins, w []bool)
+	}
+
+	// memory is a register or a RAM: out is the word at address, or at 0
+	// without an address pin, and in is written there on the clock when load
+	// is set. Words at or above writable are read only.
+	//
+	// Like in the reference simulator, the builtin clocked chips change state
+	// on the tick, while their outputs follow on the tock.
+	memory struct {
+		pins
+		words    []int16
+		writable int
+		views    map[string][]int16
+	}
+
+	counter struct {
+		pins
+		state [1]int16
+	}
+
+	// processor is the Hack CPU. outM and writeM depend on the current
+	// instruction, while addressM and pc only depend on the registers.
+	processor struct {
+		pins
+		regs [3]int16
+	}
+)
+
+func init() {
+	define(`CHIP Not { IN in; OUT out; BUILTIN Not; }`, unary(func(in int) int { return ^in }))
+	define(`CHIP Not16 { IN in[16]; OUT out[16]; BUILTIN Not16; }`, unary(func(in int) int { return ^in }))
+	define(`CHIP Inc16 { IN in[16]; OUT out[16]; BUILTIN Inc16; }`, unary(func(in int) int { return in + 1 }))
+	define(`CHIP Or8Way { IN in[8]; OUT out; BUILTIN Or8Way; }`, unary(func(in int) int { return toInt(in != 0) }))
+
+	define(`CHIP And { IN a, b; OUT out; BUILTIN And; }`, binary(func(a, b int) int { return a & b }))
+	define(`CHIP Or { IN a, b; OUT out; BUILTIN Or; }`, binary(func(a, b int) int { return a | b }))
+	define(`CHIP Xor { IN a, b; OUT out; BUILTIN Xor; }`, binary(func(a, b int) int { return a ^ b }))
+	define(`CHIP And16 { IN a[16], b[16]; OUT out[16]; BUILTIN And16; }`, binary(func(a, b int) int { return a & b }))
+	define(`CHIP Or16 { IN a[16], b[16]; OUT out[16]; BUILTIN Or16; }`, binary(func(a, b int) int { return a | b }))
+	define(`CHIP Add16 { IN a[16], b[16]; OUT out[16]; BUILTIN Add16; }`, binary(func(a, b int) int { return a + b }))
+
+	define(`CHIP Mux { IN a, b, sel; OUT out; BUILTIN Mux; }`, mux("ab"))
+	define(`CHIP Mux16 { IN a[16], b[16], sel; OUT out[16]; BUILTIN Mux16; }`, mux("ab"))
+	define(`CHIP Mux4Way16 { IN a[16], b[16], c[16], d[16], sel[2]; OUT out[16]; BUILTIN Mux4Way16; }`, mux("abcd"))
+	define(`CHIP Mux8Way16 {
+		IN a[16], b[16], c[16], d[16], e[16], f[16], g[16], h[16], sel[3];
+		OUT out[16];
+		BUILTIN Mux8Way16;
+	}`, mux("abcdefgh"))
+	define(`CHIP DMux { IN in, sel; OUT a, b; BUILTIN DMux; }`, dmux("ab"))
+	define(`CHIP DMux4Way { IN in, sel[2]; OUT a, b, c, d; BUILTIN DMux4Way; }`, dmux("abcd"))
+	define(`CHIP DMux8Way { IN in, sel[3]; OUT a, b, c, d, e, f, g, h; BUILTIN DMux8Way; }`, dmux("abcdefgh"))
+
+	define(`CHIP HalfAdder { IN a, b; OUT sum, carry; BUILTIN HalfAdder; }`, combinational(func(p pins, w []bool) {
+		sum := p.get(w, "a") + p.get(w, "b")
+		p.set(w, "sum", sum)
+		p.set(w, "carry", sum>>1)
+	}))
+	define(`CHIP FullAdder { IN a, b, c; OUT sum, carry; BUILTIN FullAdder; }`, combinational(func(p pins, w []bool) {
+		sum := p.get(w, "a") + p.get(w, "b") + p.get(w, "c")
+		p.set(w, "sum", sum)
+		p.set(w, "carry", sum>>1)
+	}))
+	define(`CHIP ALU {
+		IN x[16], y[16], zx, nx, zy, ny, f, no;
+		OUT out[16], zr, ng;
+		BUILTIN ALU;
+	}`, combinational(func(p pins, w []bool) {
+		out := alu(int16(p.get(w, "x")), int16(p.get(w, "y")), p.get(w, "zx") != 0, p.get(w, "nx") != 0,
+			p.get(w, "zy") != 0, p.get(w, "ny") != 0, p.get(w, "f") != 0, p.get(w, "no") != 0)
+		p.set(w, "out", int(out))
+		p.set(w, "zr", toInt(out == 0))
+		p.set(w, "ng", toInt(out < 0))
+	}))
+
+	define(`CHIP Bit { IN in, load; OUT out; BUILTIN Bit; CLOCKED in, load; }`, newMemory(1, 1))
+	define(`CHIP Register { IN in[16], load; OUT out[16]; BUILTIN Register; CLOCKED in, load; }`, newMemory(1, 1))
+	define(`CHIP ARegister { IN in[16], load; OUT out[16]; BUILTIN ARegister; CLOCKED in, load; }`, newMemory(1, 1))
+	define(`CHIP DRegister { IN in[16], load; OUT out[16]; BUILTIN DRegister; CLOCKED in, load; }`, newMemory(1, 1))
+	for bits, name := range map[int]string{3: "RAM8", 6: "RAM64", 9: "RAM512", 12: "RAM4K", 14: "RAM16K"} {
+		define(`CHIP `+name+` {
+			IN in[16], load, address[`+strconv.Itoa(bits)+`];
+			OUT out[16];
+			BUILTIN `+name+`;
+			CLOCKED in, load;
+		}`, newMemory(1<<bits, 1<<bits))
+	}
+	define(`CHIP Screen {
+		IN in[16], load, address[13];
+		OUT out[16];
+		BUILTIN Screen;
+		CLOCKED in, load;
+	}`, newMemory(cpu.ScreenSize, cpu.ScreenSize))
+	define(`CHIP Keyboard { OUT out[16]; BUILTIN Keyboard; }`, newMemory(1, 0))
+	define(`CHIP ROM32K { IN address[15]; OUT out[16]; BUILTIN ROM32K; }`, newMemory(cpu.ROMSize, 0))
+	define(`CHIP Memory {
+		IN in[16], load, address[15];
+		OUT out[16];
+		BUILTIN Memory;
+		CLOCKED in, load;
+	}`, func(p pins) part {
+		m := newMemory(cpu.KeyboardAddress+1, cpu.KeyboardAddress)(p).(*memory)
+		m.views["RAM16K"] = m.words[:cpu.ScreenBase]
+		m.views["Screen"] = m.words[cpu.ScreenBase:cpu.KeyboardAddress]
+		m.views["Keyboard"] = m.words[cpu.KeyboardAddress:]
+		return m
+	})
+
+	define(`CHIP PC { IN in[16], load, inc, reset; OUT out[16]; BUILTIN PC; CLOCKED in, load, inc, reset; }`, func(p pins) part {
+		return &counter{pins: p}
+	})
+	define(`CHIP CPU {
+		IN inM[16], instruction[16], reset;
+		OUT outM[16], writeM, addressM[15], pc[15];
+		BUILTIN CPU;
+		CLOCKED reset;
+	}`, func(p pins) part {
+		return &processor{pins: p}
+	})
+}
+
+func combinational(fn func(p pins, w []bool)) func(p pins) part {
+	return func(p pins) part {
+		return &logic{pins: p, fn: fn}
+	}
+}
+
+func unary(fn func(in int) int) func(p pins) part {
+	return combinational(func(p pins, w []bool) {
+		p.set(w, "out", fn(p.get(w, "in")))
+	})
+}
+
+func binary(fn func(a, b int) int) func(p pins) part {
+	return combinational(func(p pins, w []bool) {
+		p.set(w, "out", fn(p.get(w, "a"), p.get(w, "b")))
+	})
+}
+
+// mux selects the input named by the sel-th letter of names.
+func mux(names string) func(p pins) part {
+	return combinational(func(p pins, w []bool) {
+		p.set(w, "out", p.get(w, names[p.get(w, "sel"):][:1]))
+	})
+}
+
+// dmux routes in to the output named by the sel-th letter of names.
+func dmux(names string) func(p pins) part {
+	return combinational(func(p pins, w []bool) {
+		sel := p.get(w, "sel")
+		for i := range names {
+			value := 0
+			if i == sel {
+				value = p.get(w, "in")
+			}
+			p.set(w, names[i:i+1], value)
+		}
+	})
+}
+
+func newMemory(size, writable int) func(p pins) part {
+	return func(p pins) part {
+		words := make([]int16, size)
+		return &memory{pins: p, words: words, writable: writable, views: map[string][]int16{p.chip.Name: words}}
+	}
+}
+
+func alu(x, y int16, zx, nx, zy, ny, f, no bool) (out int16) {
+	if zx {
+		x = 0
+	}
+	if nx {
+		x = ^x
+	}
+	if zy {
+		y = 0
+	}
+	if ny {
+		y = ^y
+	}
+	if f {
+		out = x + y
+	} else {
+		out = x & y
+	}
+	if no {
+		out = ^out
+	}
+	return
+}
+
+func toInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func (l *logic) eval(w []bool) {
+	l.fn(l.pins, w)
+}
+
+func (m *memory) at(w []bool) int {
+	if m.has("address") {
+		return m.get(w, "address")
+	}
+	return 0
+}
+
+func (m *memory) eval(w []bool) {
+	var out int16
+	if address := m.at(w); address < len(m.words) {
+		out = m.words[address]
+	}
+	m.set(w, "out", int(out))
+}
+
+func (m *memory) tick(w []bool) {
+	if m.has("load") && m.get(w, "load") != 0 {
+		if address := m.at(w); address < m.writable {
+			m.words[address] = int16(m.get(w, "in"))
+		}
+	}
+}
+
+func (m *memory) tock() {}
+
+func (m *memory) emit(w []bool) {}
+
+func (m *memory) memories() map[string][]int16 {
+	return m.views
+}
+
+func (c *counter) eval(w []bool) {
+	c.set(w, "out", int(c.state[0]))
+}
+
+func (c *counter) tick(w []bool) {
+	switch {
+	case c.get(w, "reset") != 0:
+		c.state[0] = 0
+	case c.get(w, "load") != 0:
+		c.state[0] = int16(c.get(w, "in"))
+	case c.get(w, "inc") != 0:
+		c.state[0] += 1
+	}
+}
+
+func (c *counter) tock() {}
+
+func (c *counter) emit(w []bool) {}
+
+func (c *counter) memories() map[string][]int16 {
+	return map[string][]int16{c.chip.Name: c.state[:]}
+}
+
+func (cpu *processor) outputs() []int {
+	return append(append([]int{}, cpu.wires["outM"]...), cpu.wires["writeM"]...)
+}
+
+// execute computes the ALU output of the current instruction.
+func (cpu *processor) execute(w []bool) (instr int, out int16) {
+	instr = cpu.get(w, "instruction")
+	y := cpu.regs[regA]
+	if instr&bitA != 0 {
+		y = int16(cpu.get(w, "inM"))
+	}
+	out = alu(cpu.regs[regD], y, instr&bitZX != 0, instr&bitNX != 0, instr&bitZY != 0, instr&bitNY != 0,
+		instr&bitF != 0, instr&bitNO != 0)
+	return
+}
+
+func (cpu *processor) eval(w []bool) {
+	instr, out := cpu.execute(w)
+	cpu.set(w, "outM", int(out))
+	cpu.set(w, "writeM", toInt(instr&bitC != 0 && instr&bitDestM != 0))
+}
+
+func (cpu *processor) tick(w []bool) {
+	instr, out := cpu.execute(w)
+	next := cpu.regs
+	next[regPC] += 1
+
+	if instr&bitC == 0 {
+		next[regA] = int16(instr)
+	} else {
+		if instr&bitDestA != 0 {
+			next[regA] = out
+		}
+		if instr&bitDestD != 0 {
+			next[regD] = out
+		}
+		if instr&bitJLT != 0 && out < 0 || instr&bitJEQ != 0 && out == 0 || instr&bitJGT != 0 && out > 0 {
+			next[regPC] = cpu.regs[regA]
+		}
+	}
+
+	if cpu.get(w, "reset") != 0 {
+		next[regPC] = 0
+	}
+	cpu.regs = next
+}
+
+func (cpu *processor) tock() {}
+
+func (cpu *processor) emit(w []bool) {
+	cpu.set(w, "addressM", int(cpu.regs[regA]))
+	cpu.set(w, "pc", int(cpu.regs[regPC]))
+}
+
+func (cpu *processor) memories() map[string][]int16 {
+	return map[string][]int16{
+		"ARegister": cpu.regs[regA : regA+1],
+		"DRegister": cpu.regs[regD : regD+1],
+		"PC":        cpu.regs[regPC : regPC+1],
+	}
+}
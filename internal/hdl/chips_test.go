@@ -0,0 +1,162 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hdl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func builtin(t *testing.T, name string) *Simulator {
+	sim, err := New(Builtins[name].Chip, NewLoader())
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	return sim
+}
+
+func eval(t *testing.T, sim *Simulator, inputs map[string]int) {
+	for name, value := range inputs {
+		if !assert.Nil(t, sim.Set(name, value)) {
+			t.FailNow()
+		}
+	}
+	sim.Eval()
+}
+
+func TestBuiltinLogic(t *testing.T) {
+	sim := builtin(t, "Mux4Way16")
+	eval(t, sim, map[string]int{"a": 1, "b": 2, "c": 3, "d": -4, "sel": 3})
+	out, _ := sim.Get("out")
+	assert.Equal(t, -4, out)
+
+	sim = builtin(t, "DMux8Way")
+	eval(t, sim, map[string]int{"in": 1, "sel": 5})
+	for i, name := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		out, _ := sim.Get(name)
+		assert.Equal(t, toInt(i == 5), out, name)
+	}
+
+	sim = builtin(t, "FullAdder")
+	eval(t, sim, map[string]int{"a": 1, "b": 0, "c": 1})
+	sum, _ := sim.Get("sum")
+	carry, _ := sim.Get("carry")
+	assert.Equal(t, []int{0, 1}, []int{sum, carry})
+}
+
+func TestBuiltinALU(t *testing.T) {
+	sim := builtin(t, "ALU")
+	for _, test := range []struct {
+		inputs      map[string]int
+		out, zr, ng int
+	}{
+		{map[string]int{"x": 5, "y": 3, "zx": 0, "nx": 0, "zy": 0, "ny": 0, "f": 1, "no": 0}, 8, 0, 0},
+		{map[string]int{"x": 5, "y": 3, "zx": 0, "nx": 1, "zy": 0, "ny": 0, "f": 1, "no": 1}, 2, 0, 0},
+		{map[string]int{"x": 5, "y": 3, "zx": 0, "nx": 0, "zy": 0, "ny": 1, "f": 1, "no": 1}, -2, 0, 1},
+		{map[string]int{"x": 5, "y": 3, "zx": 1, "nx": 0, "zy": 1, "ny": 0, "f": 1, "no": 0}, 0, 1, 0},
+		{map[string]int{"x": 5, "y": 3, "zx": 0, "nx": 0, "zy": 0, "ny": 0, "f": 0, "no": 0}, 1, 0, 0},
+	} {
+		eval(t, sim, test.inputs)
+		out, _ := sim.Get("out")
+		zr, _ := sim.Get("zr")
+		ng, _ := sim.Get("ng")
+		assert.Equal(t, []int{test.out, test.zr, test.ng}, []int{out, zr, ng}, test.inputs)
+	}
+}
+
+func TestBuiltinRAM(t *testing.T) {
+	sim := builtin(t, "RAM4K")
+	eval(t, sim, map[string]int{"in": -7, "load": 1, "address": 4000})
+	sim.Tick()
+	words, ok := sim.Memory("RAM4K")
+	assert.True(t, ok)
+	assert.Equal(t, int16(-7), words[4000])
+	out, _ := sim.Get("out")
+	assert.Equal(t, 0, out)
+
+	sim.Tock()
+	out, _ = sim.Get("out")
+	assert.Equal(t, -7, out)
+
+	eval(t, sim, map[string]int{"in": 9, "load": 0, "address": 3})
+	sim.Tick()
+	sim.Tock()
+	out, _ = sim.Get("out")
+	assert.Equal(t, 0, out)
+	assert.Equal(t, int16(-7), words[4000])
+}
+
+func TestBuiltinPC(t *testing.T) {
+	sim := builtin(t, "PC")
+	outs := []int{}
+	for _, inputs := range []map[string]int{
+		{"inc": 1},
+		{"inc": 1},
+		{"in": 100, "load": 1},
+		{"load": 0, "inc": 0},
+		{"inc": 1, "reset": 1},
+	} {
+		eval(t, sim, inputs)
+		sim.Tick()
+		sim.Tock()
+		out, _ := sim.Get("out")
+		outs = append(outs, out)
+	}
+	assert.Equal(t, []int{1, 2, 100, 100, 0}, outs)
+}
+
+func TestBuiltinComputer(t *testing.T) {
+	sim, err := simulate(t, map[string]string{
+		"Computer": `CHIP Computer {
+    IN reset;
+    PARTS:
+    ROM32K(address=pc, out=instruction);
+    CPU(inM=inM, instruction=instruction, reset=reset, outM=outM, writeM=writeM, addressM=addressM, pc=pc);
+    Memory(in=outM, load=writeM, address=addressM, out=inM);
+}`,
+	}, "Computer")
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	// RAM[2] = RAM[0] + RAM[1]
+	rom, _ := sim.Memory("ROM32K")
+	copy(rom, []int16{0, -1008, 1, -3952, 2, -7416})
+	ram, _ := sim.Memory("RAM16K")
+	ram[0], ram[1] = 30, 12
+	sim.Eval()
+
+	for range 6 {
+		sim.Tick()
+		sim.Tock()
+	}
+	assert.Equal(t, int16(42), ram[2])
+
+	pc, _ := sim.Memory("PC")
+	assert.Equal(t, int16(6), pc[0])
+	d, _ := sim.Memory("DRegister")
+	assert.Equal(t, int16(42), d[0])
+}
+
+func TestLoaderForceHDL(t *testing.T) {
+	files := map[string]string{
+		// A deliberately wrong Not, to tell it from the builtin.
+		"Not":  `CHIP Not { IN in; OUT out; PARTS: Nand(a=in, b=true, out=n); Nand(a=n, b=true, out=out); }`,
+		"Pass": `CHIP Pass { IN in; OUT out; PARTS: Not(in=in, out=out); }`,
+	}
+
+	sim, err := simulate(t, files, "Pass")
+	assert.Nil(t, err)
+	eval(t, sim, map[string]int{"in": 1})
+	out, _ := sim.Get("out")
+	assert.Equal(t, 0, out)
+
+	sim, err = simulate(t, files, "Pass", "Not")
+	assert.Nil(t, err)
+	eval(t, sim, map[string]int{"in": 1})
+	out, _ = sim.Get("out")
+	assert.Equal(t, 1, out)
+}
@@ -0,0 +1,46 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hdl
+
+const (
+	SignalTrue  = "true"
+	SignalFalse = "false"
+
+	// MaxWidth is the widest bus of the Hack chips.
+	MaxWidth = 16
+)
+
+// Wires 0 and 1 always carry the constants false and true.
+const (
+	wireFalse = iota
+	wireTrue
+)
+
+// Registers of the builtin CPU.
+const (
+	regA = iota
+	regD
+	regPC
+)
+
+// Instruction bits decoded by the builtin CPU.
+const (
+	bitC  = 1 << 15
+	bitA  = 1 << 12
+	bitZX = 1 << 11
+	bitNX = 1 << 10
+	bitZY = 1 << 9
+	bitNY = 1 << 8
+	bitF  = 1 << 7
+	bitNO = 1 << 6
+
+	bitDestA = 1 << 5
+	bitDestD = 1 << 4
+	bitDestM = 1 << 3
+
+	bitJLT = 1 << 2
+	bitJEQ = 1 << 1
+	bitJGT = 1 << 0
+)
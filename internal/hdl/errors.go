@@ -0,0 +1,93 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hdl
+
+import (
+	"errors"
+	"strconv"
+)
+
+var (
+	ErrUnexpectedEOF       = errors.New("unexpected end of file")
+	ErrCommentUnterminated = errors.New("comment not terminated")
+	ErrCombinationalLoop   = errors.New("combinational loop")
+)
+
+type (
+	ErrUnexpectedToken struct {
+		token    string
+		expected string
+	}
+
+	ErrChipUndefined struct {
+		chip string
+	}
+
+	ErrPinUndefined struct {
+		chip string
+		pin  string
+	}
+
+	ErrSignalUndefined struct {
+		signal string
+	}
+
+	ErrWidthMismatch struct {
+		pin    string
+		signal string
+	}
+
+	ErrRangeInvalid struct {
+		bus string
+	}
+
+	ErrPinNotInput struct {
+		pin string
+	}
+
+	ErrInputDriven struct {
+		signal string
+	}
+
+	ErrChipRecursive struct {
+		chip string
+	}
+)
+
+func (err ErrUnexpectedToken) Error() string {
+	return "unexpected " + strconv.Quote(err.token) + ", expected " + err.expected
+}
+
+func (err ErrChipUndefined) Error() string {
+	return "undefined chip: " + err.chip
+}
+
+func (err ErrPinUndefined) Error() string {
+	return "undefined pin of " + err.chip + ": " + err.pin
+}
+
+func (err ErrSignalUndefined) Error() string {
+	return "undefined signal: " + err.signal
+}
+
+func (err ErrWidthMismatch) Error() string {
+	return "width mismatch connecting " + err.pin + " to " + err.signal
+}
+
+func (err ErrRangeInvalid) Error() string {
+	return "invalid sub-bus: " + err.bus
+}
+
+func (err ErrPinNotInput) Error() string {
+	return "not an input pin: " + err.pin
+}
+
+func (err ErrInputDriven) Error() string {
+	return "input pin driven by a part: " + err.signal
+}
+
+func (err ErrChipRecursive) Error() string {
+	return "chip contains itself: " + err.chip
+}
@@ -0,0 +1,65 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hdl
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+type (
+	// Loader finds the chips used as parts, caching them by name. Builtin
+	// chips are preferred to the .hdl files on the path.
+	Loader struct {
+		// Path lists the directories searched for Name.hdl.
+		Path []string
+
+		// ForceHDL names the chips loaded from their .hdl file even though
+		// a builtin exists.
+		ForceHDL map[string]bool
+
+		chips map[string]*Chip
+	}
+)
+
+func NewLoader(path ...string) *Loader {
+	return &Loader{Path: path, ForceHDL: map[string]bool{}, chips: map[string]*Chip{}}
+}
+
+// LoadFile parses the chip at filePath.
+func (l *Loader) LoadFile(filePath string) (chip *Chip, err error) {
+	var file *os.File
+	if file, err = os.Open(filePath); err != nil {
+		return
+	}
+	defer file.Close()
+
+	return ParseFile(filePath, file)
+}
+
+// Load returns the chip named name.
+func (l *Loader) Load(name string) (chip *Chip, err error) {
+	if chip, ok := l.chips[name]; ok {
+		return chip, nil
+	}
+	if builtin, ok := Builtins[name]; ok && !l.ForceHDL[name] {
+		l.chips[name] = builtin.Chip
+		return builtin.Chip, nil
+	}
+
+	for _, dir := range l.Path {
+		chip, err = l.LoadFile(filepath.Join(dir, name+".hdl"))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return
+		}
+		l.chips[name] = chip
+		return
+	}
+	return nil, ErrChipUndefined{chip: name}
+}
@@ -0,0 +1,330 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hdl
+
+import (
+	"hack/internal/diag"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type (
+	token struct {
+		text   string
+		line   int
+		column int
+	}
+
+	parser struct {
+		name   string
+		lines  []string
+		tokens []token
+		pos    int
+	}
+)
+
+func ParseString(str string) (chip *Chip, err error) {
+	return Parse(strings.NewReader(str))
+}
+
+func Parse(r io.Reader) (chip *Chip, err error) {
+	return ParseFile("", r)
+}
+
+// ParseFile parses the chip definition read from r.
+func ParseFile(name string, r io.Reader) (chip *Chip, err error) {
+	var src []byte
+	if src, err = io.ReadAll(r); err != nil {
+		return
+	}
+
+	p := &parser{name: name, lines: strings.Split(string(src), "\n")}
+	if err = p.tokenize(string(src)); err != nil {
+		return
+	}
+	if chip, err = p.chip(); err != nil {
+		return
+	}
+	chip.File = name
+	return
+}
+
+func (p *parser) error(line, column int, err error) diag.Diagnostic {
+	d := diag.Diagnostic{File: p.name, Line: line, Column: column, Err: err}
+	if line > 0 && line <= len(p.lines) {
+		d.Source = strings.TrimRight(p.lines[line-1], "\r")
+	}
+	return d
+}
+
+func (p *parser) tokenize(src string) (err error) {
+	line, column := 1, 1
+	advance := func(n int) {
+		for _, char := range src[:n] {
+			if char == '\n' {
+				line += 1
+				column = 1
+			} else {
+				column += 1
+			}
+		}
+		src = src[n:]
+	}
+
+	for len(src) > 0 {
+		switch char := src[0]; {
+		case char == ' ' || char == '\t' || char == '\r' || char == '\n':
+			advance(1)
+		case strings.HasPrefix(src, "//"):
+			end := strings.IndexByte(src, '\n')
+			if end < 0 {
+				end = len(src)
+			}
+			advance(end)
+		case strings.HasPrefix(src, "/*"):
+			end := strings.Index(src[2:], "*/")
+			if end < 0 {
+				return p.error(line, column, ErrCommentUnterminated)
+			}
+			advance(end + 4)
+		case strings.HasPrefix(src, ".."):
+			p.tokens = append(p.tokens, token{text: "..", line: line, column: column})
+			advance(2)
+		case isWordChar(char):
+			end := 1
+			for end < len(src) && isWordChar(src[end]) {
+				end += 1
+			}
+			p.tokens = append(p.tokens, token{text: src[:end], line: line, column: column})
+			advance(end)
+		default:
+			p.tokens = append(p.tokens, token{text: src[:1], line: line, column: column})
+			advance(1)
+		}
+	}
+	return
+}
+
+func isWordChar(char byte) bool {
+	return char >= 'a' && char <= 'z' || char >= 'A' && char <= 'Z' || char >= '0' && char <= '9' || char == '_' || char == '-'
+}
+
+func isNumber(text string) bool {
+	for _, char := range []byte(text) {
+		if char < '0' || char > '9' {
+			return false
+		}
+	}
+	return text != ""
+}
+
+func (p *parser) at(text string) bool {
+	return p.pos < len(p.tokens) && p.tokens[p.pos].text == text
+}
+
+func (p *parser) unexpected(expected string) error {
+	if p.pos >= len(p.tokens) {
+		line := len(p.lines)
+		return p.error(line, len(p.lines[line-1])+1, ErrUnexpectedEOF)
+	}
+	tok := p.tokens[p.pos]
+	return p.error(tok.line, tok.column, ErrUnexpectedToken{token: tok.text, expected: expected})
+}
+
+func (p *parser) expect(text string) (err error) {
+	if !p.at(text) {
+		return p.unexpected(strconv.Quote(text))
+	}
+	p.pos += 1
+	return
+}
+
+func (p *parser) identifier() (name string, err error) {
+	if p.pos >= len(p.tokens) || !isWordChar(p.tokens[p.pos].text[0]) || isNumber(p.tokens[p.pos].text[:1]) {
+		return "", p.unexpected("identifier")
+	}
+	name = p.tokens[p.pos].text
+	p.pos += 1
+	return
+}
+
+func (p *parser) number() (value int, err error) {
+	if p.pos >= len(p.tokens) || !isNumber(p.tokens[p.pos].text) {
+		return 0, p.unexpected("number")
+	}
+	value, _ = strconv.Atoi(p.tokens[p.pos].text)
+	p.pos += 1
+	return
+}
+
+func (p *parser) chip() (chip *Chip, err error) {
+	chip = &Chip{}
+	if err = p.expect("CHIP"); err != nil {
+		return
+	}
+	if chip.Name, err = p.identifier(); err != nil {
+		return
+	}
+	if err = p.expect("{"); err != nil {
+		return
+	}
+
+	if p.at("IN") {
+		p.pos += 1
+		if chip.Inputs, err = p.pins(); err != nil {
+			return
+		}
+	}
+	if p.at("OUT") {
+		p.pos += 1
+		if chip.Outputs, err = p.pins(); err != nil {
+			return
+		}
+	}
+
+	switch {
+	case p.at("PARTS"):
+		p.pos += 1
+		if err = p.expect(":"); err != nil {
+			return
+		}
+		for !p.at("}") {
+			var part Part
+			if part, err = p.part(); err != nil {
+				return
+			}
+			chip.Parts = append(chip.Parts, part)
+		}
+	case p.at("BUILTIN"):
+		p.pos += 1
+		if chip.Builtin, err = p.identifier(); err != nil {
+			return
+		}
+		if err = p.expect(";"); err != nil {
+			return
+		}
+		if p.at("CLOCKED") {
+			p.pos += 1
+			if chip.Clocked, err = p.names(); err != nil {
+				return
+			}
+		}
+	default:
+		return nil, p.unexpected("PARTS or BUILTIN")
+	}
+
+	if err = p.expect("}"); err != nil {
+		return
+	}
+	if p.pos < len(p.tokens) {
+		return nil, p.unexpected("end of file")
+	}
+	return
+}
+
+// pins parses a pin list ending with a semicolon.
+func (p *parser) pins() (pins []Pin, err error) {
+	for {
+		pin := Pin{Width: 1}
+		if pin.Name, err = p.identifier(); err != nil {
+			return
+		}
+		if p.at("[") {
+			p.pos += 1
+			if pin.Width, err = p.number(); err != nil {
+				return
+			}
+			if err = p.expect("]"); err != nil {
+				return
+			}
+		}
+		pins = append(pins, pin)
+
+		if !p.at(",") {
+			return pins, p.expect(";")
+		}
+		p.pos += 1
+	}
+}
+
+// names parses a list of names ending with a semicolon.
+func (p *parser) names() (names []string, err error) {
+	for {
+		var name string
+		if name, err = p.identifier(); err != nil {
+			return
+		}
+		names = append(names, name)
+
+		if !p.at(",") {
+			return names, p.expect(";")
+		}
+		p.pos += 1
+	}
+}
+
+func (p *parser) part() (part Part, err error) {
+	if p.pos < len(p.tokens) {
+		part.Line, part.Column = p.tokens[p.pos].line, p.tokens[p.pos].column
+	}
+	if part.Name, err = p.identifier(); err != nil {
+		return
+	}
+	if err = p.expect("("); err != nil {
+		return
+	}
+	for !p.at(")") {
+		if len(part.Conns) > 0 {
+			if err = p.expect(","); err != nil {
+				return
+			}
+		}
+		var conn Connection
+		if conn.Pin, err = p.bus(); err != nil {
+			return
+		}
+		if err = p.expect("="); err != nil {
+			return
+		}
+		if conn.Signal, err = p.bus(); err != nil {
+			return
+		}
+		part.Conns = append(part.Conns, conn)
+	}
+	p.pos += 1
+	err = p.expect(";")
+	return
+}
+
+func (p *parser) bus() (bus Bus, err error) {
+	if bus.Name, err = p.identifier(); err != nil {
+		return
+	}
+	if !p.at("[") {
+		return
+	}
+	p.pos += 1
+	bus.Ranged = true
+
+	if bus.Lo, err = p.number(); err != nil {
+		return
+	}
+	bus.Hi = bus.Lo
+	if p.at("..") {
+		p.pos += 1
+		if bus.Hi, err = p.number(); err != nil {
+			return
+		}
+	}
+	if err = p.expect("]"); err != nil {
+		return
+	}
+	if bus.Hi < bus.Lo {
+		tok := p.tokens[p.pos-1]
+		err = p.error(tok.line, tok.column, ErrRangeInvalid{bus: bus.Name})
+	}
+	return
+}
@@ -0,0 +1,75 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hdl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	chip, err := ParseString(`
+// Selects the low or high byte.
+CHIP Pick {
+    IN in[16], /* which byte */ sel;
+    OUT out[8], zero;
+
+    PARTS:
+    Mux8(a=in[0..7], b=in[8..15], sel=sel, out=out, out[0]=low);
+    Not(in=true, out=zero);
+}`)
+	assert.Nil(t, err)
+	assert.Equal(t, &Chip{
+		Name:    "Pick",
+		Inputs:  []Pin{{Name: "in", Width: 16}, {Name: "sel", Width: 1}},
+		Outputs: []Pin{{Name: "out", Width: 8}, {Name: "zero", Width: 1}},
+		Parts: []Part{
+			{
+				Name: "Mux8",
+				Conns: []Connection{
+					{Pin: Bus{Name: "a"}, Signal: Bus{Name: "in", Ranged: true, Lo: 0, Hi: 7}},
+					{Pin: Bus{Name: "b"}, Signal: Bus{Name: "in", Ranged: true, Lo: 8, Hi: 15}},
+					{Pin: Bus{Name: "sel"}, Signal: Bus{Name: "sel"}},
+					{Pin: Bus{Name: "out"}, Signal: Bus{Name: "out"}},
+					{Pin: Bus{Name: "out", Ranged: true, Lo: 0, Hi: 0}, Signal: Bus{Name: "low"}},
+				},
+				Line:   8,
+				Column: 5,
+			},
+			{
+				Name: "Not",
+				Conns: []Connection{
+					{Pin: Bus{Name: "in"}, Signal: Bus{Name: "true"}},
+					{Pin: Bus{Name: "out"}, Signal: Bus{Name: "zero"}},
+				},
+				Line:   9,
+				Column: 5,
+			},
+		},
+	}, chip)
+}
+
+func TestParseBuiltin(t *testing.T) {
+	chip, err := ParseString(`CHIP Bit { IN in, load; OUT out; BUILTIN Bit; CLOCKED in, load; }`)
+	assert.Nil(t, err)
+	assert.Equal(t, "Bit", chip.Builtin)
+	assert.Equal(t, []string{"in", "load"}, chip.Clocked)
+	assert.Nil(t, chip.Parts)
+}
+
+func TestParseErrors(t *testing.T) {
+	for src, msg := range map[string]string{
+		"CHIP A { IN a; OUT b; PARTS: Not(in=a, out=b) }": `1:47: unexpected "}", expected ";"`,
+		"CHIP A { IN a[16]; PARTS: Not(in=a[3..1]); }":    "1:40: invalid sub-bus: a",
+		"CHIP A { /* IN a; }":                             "1:10: comment not terminated",
+		"CHIP A { IN a;":                                  "unexpected end of file",
+	} {
+		_, err := ParseString(src)
+		if assert.NotNil(t, err, src) {
+			assert.Contains(t, err.Error(), msg, src)
+		}
+	}
+}
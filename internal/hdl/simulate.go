@@ -0,0 +1,343 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hdl
+
+import (
+	"hack/internal/diag"
+	"slices"
+)
+
+type (
+	// Simulator flattens a chip into the builtin parts it is made of, and
+	// simulates them wire by wire.
+	Simulator struct {
+		Chip *Chip
+
+		wires    []bool
+		pins     map[string][]int
+		parts    []part
+		clocked  []clockedPart
+		memories map[string][]int16
+	}
+
+	// elaborator expands chips into builtin parts. Wires are merged with
+	// union-find as part outputs are connected to signals.
+	elaborator struct {
+		loader *Loader
+		parent []int
+		parts  []part
+		stack  []string
+	}
+)
+
+func New(chip *Chip, loader *Loader) (sim *Simulator, err error) {
+	e := &elaborator{loader: loader, parent: []int{wireFalse, wireTrue}}
+
+	pins := map[string][]int{}
+	for _, pin := range slices.Concat(chip.Inputs, chip.Outputs) {
+		pins[pin.Name] = e.newWires(pin.Width)
+	}
+	if err = e.instantiate(chip, pins); err != nil {
+		return
+	}
+
+	sim = &Simulator{Chip: chip, wires: make([]bool, len(e.parent)), pins: pins, memories: map[string][]int16{}}
+	for _, wires := range pins {
+		e.resolve(wires)
+	}
+
+	for _, p := range e.parts {
+		p.remap(e.find)
+		if p, ok := p.(clockedPart); ok {
+			sim.clocked = append(sim.clocked, p)
+		}
+		if p, ok := p.(memoryPart); ok {
+			for name, words := range p.memories() {
+				if _, ok := sim.memories[name]; !ok {
+					sim.memories[name] = words
+				}
+			}
+		}
+	}
+
+	if sim.parts, err = sortParts(e.parts, len(sim.wires)); err != nil {
+		return
+	}
+	sim.wires[wireTrue] = true
+	sim.Eval()
+	return
+}
+
+func (e *elaborator) newWires(width int) (wires []int) {
+	wires = make([]int, width)
+	for i := range wires {
+		wires[i] = len(e.parent)
+		e.parent = append(e.parent, len(e.parent))
+	}
+	return
+}
+
+func (e *elaborator) find(wire int) int {
+	for e.parent[wire] != wire {
+		e.parent[wire] = e.parent[e.parent[wire]]
+		wire = e.parent[wire]
+	}
+	return wire
+}
+
+func (e *elaborator) union(a, b int) {
+	e.parent[e.find(a)] = e.find(b)
+}
+
+func (e *elaborator) resolve(wires []int) {
+	for i, wire := range wires {
+		wires[i] = e.find(wire)
+	}
+}
+
+// instantiate connects the parts of chip to each other and to the wires of
+// its pins.
+func (e *elaborator) instantiate(chip *Chip, pins map[string][]int) (err error) {
+	if chip.Builtin != "" {
+		builtin, ok := Builtins[chip.Builtin]
+		if !ok {
+			return ErrChipUndefined{chip: chip.Builtin}
+		}
+		e.parts = append(e.parts, builtin.New(pins))
+		return
+	}
+
+	if slices.Contains(e.stack, chip.Name) {
+		return ErrChipRecursive{chip: chip.Name}
+	}
+	e.stack = append(e.stack, chip.Name)
+	defer func() { e.stack = e.stack[:len(e.stack)-1] }()
+
+	parts := make([]*Chip, len(chip.Parts))
+	for i, p := range chip.Parts {
+		if parts[i], err = e.loader.Load(p.Name); err != nil {
+			return partError(chip, p, err)
+		}
+	}
+
+	// Internal signals are those driven by part outputs, sized by the pins
+	// driving them.
+	signals := map[string][]int{}
+	for name, wires := range pins {
+		signals[name] = wires
+	}
+	for i, p := range chip.Parts {
+		for _, conn := range p.Conns {
+			pin, ok := parts[i].Pin(conn.Pin.Name)
+			if !ok {
+				return partError(chip, p, ErrPinUndefined{chip: p.Name, pin: conn.Pin.Name})
+			}
+			if parts[i].IsInput(pin.Name) || signals[conn.Signal.Name] != nil {
+				continue
+			}
+			if conn.Signal.Ranged {
+				return partError(chip, p, ErrRangeInvalid{bus: conn.Signal.Name})
+			}
+			signals[conn.Signal.Name] = e.newWires(conn.Pin.Width(pin.Width))
+		}
+	}
+
+	for i, p := range chip.Parts {
+		if err = e.connect(chip, p, parts[i], signals); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// connect wires the pins of one part and instantiates it. Unconnected inputs
+// read false.
+func (e *elaborator) connect(chip *Chip, p Part, partChip *Chip, signals map[string][]int) (err error) {
+	pins := map[string][]int{}
+	for _, pin := range partChip.Inputs {
+		pins[pin.Name] = make([]int, pin.Width)
+	}
+	for _, pin := range partChip.Outputs {
+		pins[pin.Name] = e.newWires(pin.Width)
+	}
+
+	for _, conn := range p.Conns {
+		pin, _ := partChip.Pin(conn.Pin.Name)
+		input := partChip.IsInput(pin.Name)
+		if conn.Pin.Ranged && conn.Pin.Hi >= pin.Width {
+			return partError(chip, p, ErrRangeInvalid{bus: conn.Pin.Name})
+		}
+		pinWires := pins[pin.Name][conn.Pin.Lo : conn.Pin.Lo+conn.Pin.Width(pin.Width)]
+
+		var sigWires []int
+		switch name := conn.Signal.Name; {
+		case name == SignalTrue || name == SignalFalse:
+			if !input {
+				return partError(chip, p, ErrInputDriven{signal: name})
+			}
+			wire := wireFalse
+			if name == SignalTrue {
+				wire = wireTrue
+			}
+			for i := range pinWires {
+				pinWires[i] = wire
+			}
+			continue
+		case !input && chip.IsInput(name):
+			return partError(chip, p, ErrInputDriven{signal: name})
+		default:
+			var ok bool
+			if sigWires, ok = signals[name]; !ok {
+				return partError(chip, p, ErrSignalUndefined{signal: name})
+			}
+		}
+
+		if conn.Signal.Ranged {
+			if conn.Signal.Hi >= len(sigWires) {
+				return partError(chip, p, ErrRangeInvalid{bus: conn.Signal.Name})
+			}
+			sigWires = sigWires[conn.Signal.Lo : conn.Signal.Hi+1]
+		}
+		if len(sigWires) != len(pinWires) {
+			return partError(chip, p, ErrWidthMismatch{pin: conn.Pin.Name, signal: conn.Signal.Name})
+		}
+
+		for i := range pinWires {
+			if input {
+				pinWires[i] = sigWires[i]
+			} else {
+				e.union(pinWires[i], sigWires[i])
+			}
+		}
+	}
+
+	return e.instantiate(partChip, pins)
+}
+
+func partError(chip *Chip, p Part, err error) error {
+	if _, ok := err.(diag.Diagnostic); ok {
+		return err
+	}
+	return diag.Diagnostic{File: chip.File, Line: p.Line, Column: p.Column, Err: err}
+}
+
+// sortParts orders parts so that each is evaluated after the parts driving
+// its inputs.
+func sortParts(parts []part, wires int) (sorted []part, err error) {
+	drivers := make([]int, wires)
+	for i := range drivers {
+		drivers[i] = -1
+	}
+	for i, p := range parts {
+		for _, wire := range p.outputs() {
+			drivers[wire] = i
+		}
+	}
+
+	pending := make([]int, len(parts))
+	users := make([][]int, len(parts))
+	for i, p := range parts {
+		for _, wire := range p.inputs() {
+			if driver := drivers[wire]; driver >= 0 {
+				pending[i] += 1
+				users[driver] = append(users[driver], i)
+			}
+		}
+	}
+
+	queue := []int{}
+	for i := range parts {
+		if pending[i] == 0 {
+			queue = append(queue, i)
+		}
+	}
+	for len(queue) > 0 {
+		i := queue[0]
+		queue = queue[1:]
+		sorted = append(sorted, parts[i])
+		for _, user := range users[i] {
+			if pending[user] -= 1; pending[user] == 0 {
+				queue = append(queue, user)
+			}
+		}
+	}
+
+	if len(sorted) != len(parts) {
+		return nil, ErrCombinationalLoop
+	}
+	return
+}
+
+// Eval propagates the inputs and the state of the clocked parts through the
+// combinational parts.
+func (sim *Simulator) Eval() {
+	for _, p := range sim.clocked {
+		p.emit(sim.wires)
+	}
+	for _, p := range sim.parts {
+		p.eval(sim.wires)
+	}
+}
+
+// Tick samples the inputs of the clocked parts.
+func (sim *Simulator) Tick() {
+	sim.Eval()
+	for _, p := range sim.clocked {
+		p.tick(sim.wires)
+	}
+}
+
+// Tock updates the clocked parts and the outputs depending on them.
+func (sim *Simulator) Tock() {
+	for _, p := range sim.clocked {
+		p.tock()
+	}
+	sim.Eval()
+}
+
+// Width returns the width of the pin named name, or 0 if there is none.
+func (sim *Simulator) Width(name string) int {
+	return len(sim.pins[name])
+}
+
+// Get returns the value of a pin. 16-bit pins are signed, narrower ones are
+// unsigned.
+func (sim *Simulator) Get(name string) (value int, err error) {
+	wires, ok := sim.pins[name]
+	if !ok {
+		return 0, ErrPinUndefined{chip: sim.Chip.Name, pin: name}
+	}
+	for i, wire := range wires {
+		if sim.wires[wire] {
+			value |= 1 << i
+		}
+	}
+	if len(wires) == MaxWidth {
+		value = int(int16(value))
+	}
+	return
+}
+
+// Set sets an input pin to value, truncated to its width.
+func (sim *Simulator) Set(name string, value int) (err error) {
+	wires, ok := sim.pins[name]
+	if !ok {
+		return ErrPinUndefined{chip: sim.Chip.Name, pin: name}
+	} else if !sim.Chip.IsInput(name) {
+		return ErrPinNotInput{pin: name}
+	}
+	for i, wire := range wires {
+		sim.wires[wire] = value&(1<<i) != 0
+	}
+	return
+}
+
+// Memory returns the words of the builtin part named name, such as RAM16K or
+// ARegister, which scripts may read and write. If the chip has several such
+// parts, the first one is returned.
+func (sim *Simulator) Memory(name string) (words []int16, ok bool) {
+	words, ok = sim.memories[name]
+	return
+}
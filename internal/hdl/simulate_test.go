@@ -0,0 +1,132 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hdl
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func simulate(t *testing.T, files map[string]string, name string, force ...string) (*Simulator, error) {
+	dir := t.TempDir()
+	for file, src := range files {
+		if !assert.Nil(t, os.WriteFile(filepath.Join(dir, file+".hdl"), []byte(src), 0o644)) {
+			t.FailNow()
+		}
+	}
+
+	loader := NewLoader(dir)
+	for _, name := range force {
+		loader.ForceHDL[name] = true
+	}
+	chip, err := loader.Load(name)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	return New(chip, loader)
+}
+
+var gates = map[string]string{
+	"Not": `CHIP Not { IN in; OUT out; PARTS: Nand(a=in, b=in, out=out); }`,
+	"And": `CHIP And { IN a, b; OUT out; PARTS: Nand(a=a, b=b, out=n); Not(in=n, out=out); }`,
+}
+
+func TestSimulateCombinational(t *testing.T) {
+	files := map[string]string{
+		"Swap": `CHIP Swap {
+    IN in[4];
+    OUT out[4], both;
+    PARTS:
+    Not(in=in[0], out=n0);
+    Not(in=n0, out=out[3]);
+    Not(in=in[3], out=n3);
+    Not(in=n3, out=out[0], out=low);
+    And(a=low, b=in[1], out=both);
+    Not(in=false, out=out[1]);
+}`,
+	}
+	for name, src := range gates {
+		files[name] = src
+	}
+
+	sim, err := simulate(t, files, "Swap")
+	assert.Nil(t, err)
+	assert.Equal(t, 4, sim.Width("out"))
+	assert.Equal(t, 1, sim.Width("both"))
+
+	for in, out := range map[int]int{0b0000: 0b0010, 0b0001: 0b1010, 0b1000: 0b0011, 0b1010: 0b0011, 0b1011: 0b1011} {
+		assert.Nil(t, sim.Set("in", in))
+		sim.Eval()
+		value, err := sim.Get("out")
+		assert.Nil(t, err)
+		assert.Equal(t, out, value, "in=%04b", in)
+		value, _ = sim.Get("both")
+		assert.Equal(t, (in>>3)&(in>>1)&1, value, "in=%04b", in)
+	}
+
+	assert.Equal(t, ErrPinNotInput{pin: "out"}, sim.Set("out", 1))
+	_, err = sim.Get("nope")
+	assert.Equal(t, ErrPinUndefined{chip: "Swap", pin: "nope"}, err)
+}
+
+func TestSimulateClocked(t *testing.T) {
+	files := map[string]string{
+		"Toggle": `CHIP Toggle {
+    IN en;
+    OUT out;
+    PARTS:
+    Not(in=prev, out=flip);
+    And(a=en, b=flip, out=set);
+    Not(in=en, out=hold);
+    And(a=hold, b=prev, out=keep);
+    Not(in=set, out=nset);
+    Not(in=keep, out=nkeep);
+    Nand(a=nset, b=nkeep, out=next);
+    DFF(in=next, out=out, out=prev);
+}`,
+	}
+	for name, src := range gates {
+		files[name] = src
+	}
+
+	sim, err := simulate(t, files, "Toggle")
+	assert.Nil(t, err)
+
+	outs := []int{}
+	for _, en := range []int{1, 1, 0, 1} {
+		sim.Set("en", en)
+		sim.Tick()
+		out, _ := sim.Get("out")
+		outs = append(outs, out)
+		sim.Tock()
+		out, _ = sim.Get("out")
+		outs = append(outs, out)
+	}
+	assert.Equal(t, []int{0, 1, 1, 0, 0, 0, 0, 1}, outs)
+}
+
+func TestSimulateErrors(t *testing.T) {
+	for src, msg := range map[string]string{
+		`CHIP A { IN a; OUT b; PARTS: Nand(a=a, b=x, out=b); }`:                           "1:30: undefined signal: x",
+		`CHIP A { IN a; OUT b; PARTS: Nand(a=a, c=a, out=b); }`:                           "undefined pin of Nand: c",
+		`CHIP A { IN a; OUT b; PARTS: Nand(a=a, b=a, out=a); }`:                           "input pin driven by a part: a",
+		`CHIP A { IN a[2]; OUT b; PARTS: Nand(a=a, b=a[0], out=b); }`:                     "width mismatch connecting a to a",
+		`CHIP A { IN a; OUT b[2]; PARTS: Nand(a=a, b=a, out=b); }`:                        "width mismatch connecting out to b",
+		`CHIP A { IN a; OUT b; PARTS: Nand(a=a, b=a, out=b[1]); }`:                        "invalid sub-bus: b",
+		`CHIP A { IN a; OUT b; PARTS: Nand(a=a, b=c, out=c); Nor(in=c); }`:                "undefined chip: Nor",
+		`CHIP A { IN a; OUT b; PARTS: Nand(a=a, b=c, out=c); }`:                           "combinational loop",
+		`CHIP A { IN a; OUT b; PARTS: A(a=a, b=b); }`:                                     "chip contains itself: A",
+		`CHIP A { IN a; OUT b; PARTS: Nand(a=a, b=a, out=true); }`:                        "input pin driven by a part: true",
+		`CHIP A { IN a; OUT b; PARTS: Nand(a=a, b=a, out=c[0]); Nand(a=c, b=c, out=b); }`: "invalid sub-bus: c",
+	} {
+		_, err := simulate(t, map[string]string{"A": src}, "A")
+		if assert.NotNil(t, err, src) {
+			assert.Contains(t, err.Error(), msg, src)
+		}
+	}
+}
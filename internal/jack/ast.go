@@ -0,0 +1,161 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jack
+
+type (
+	// Pos is the position of a node in its source file.
+	Pos struct {
+		Line   int
+		Column int
+	}
+
+	Class struct {
+		Pos
+		Name        string
+		Vars        []ClassVarDec
+		Subroutines []SubroutineDec
+	}
+
+	ClassVarDec struct {
+		Pos
+		Kind  string
+		Type  string
+		Names []string
+	}
+
+	SubroutineDec struct {
+		Pos
+		Kind       string
+		ReturnType string
+		Name       string
+		Params     []Parameter
+		Vars       []VarDec
+		Statements []Statement
+	}
+
+	Parameter struct {
+		Pos
+		Type string
+		Name string
+	}
+
+	VarDec struct {
+		Pos
+		Type  string
+		Names []string
+	}
+
+	Statement interface {
+		statement()
+	}
+
+	LetStatement struct {
+		Pos
+		Name  string
+		Index *Expression
+		Value Expression
+	}
+
+	IfStatement struct {
+		Pos
+		Cond Expression
+		Then []Statement
+		// Else is nil without an else clause, and empty for "else {}".
+		Else []Statement
+	}
+
+	WhileStatement struct {
+		Pos
+		Cond Expression
+		Body []Statement
+	}
+
+	DoStatement struct {
+		Pos
+		Call SubroutineCall
+	}
+
+	ReturnStatement struct {
+		Pos
+		Value *Expression
+	}
+
+	// Expression is a term followed by binary operations, evaluated left to
+	// right without precedence.
+	Expression struct {
+		Term Term
+		Ops  []BinaryOp
+	}
+
+	BinaryOp struct {
+		Op   string
+		Term Term
+	}
+
+	Term interface {
+		term()
+	}
+
+	IntegerConstant struct {
+		Pos
+		Value int
+	}
+
+	StringConstant struct {
+		Pos
+		Value string
+	}
+
+	KeywordConstant struct {
+		Pos
+		Value string
+	}
+
+	VarTerm struct {
+		Pos
+		Name string
+	}
+
+	IndexTerm struct {
+		Pos
+		Name  string
+		Index Expression
+	}
+
+	// SubroutineCall calls Name on Receiver, a class or a variable, or on the
+	// current object if Receiver is empty.
+	SubroutineCall struct {
+		Pos
+		Receiver string
+		Name     string
+		Args     []Expression
+	}
+
+	ParenTerm struct {
+		Pos
+		Expr Expression
+	}
+
+	UnaryTerm struct {
+		Pos
+		Op   string
+		Term Term
+	}
+)
+
+func (*LetStatement) statement()    {}
+func (*IfStatement) statement()     {}
+func (*WhileStatement) statement()  {}
+func (*DoStatement) statement()     {}
+func (*ReturnStatement) statement() {}
+
+func (*IntegerConstant) term() {}
+func (*StringConstant) term()  {}
+func (*KeywordConstant) term() {}
+func (*VarTerm) term()         {}
+func (*IndexTerm) term()       {}
+func (*SubroutineCall) term()  {}
+func (*ParenTerm) term()       {}
+func (*UnaryTerm) term()       {}
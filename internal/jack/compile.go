@@ -0,0 +1,396 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jack
+
+import (
+	"bytes"
+	"hack/internal/diag"
+	"hack/internal/vm"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type (
+	compiler struct {
+		name  string
+		lines []string
+
+		class       *Class
+		subroutines map[string]SubroutineDec
+		classSyms   *SymbolTable
+
+		subroutine SubroutineDec
+		syms       *SymbolTable
+		labels     int
+
+		prog vm.Program
+	}
+)
+
+var binaryOpToStatement = map[string]vm.Statement{
+	"+": {Command: vm.CommandAdd},
+	"-": {Command: vm.CommandSub},
+	"*": {Command: vm.CommandCall, Function: "Math.multiply", Count: 2},
+	"/": {Command: vm.CommandCall, Function: "Math.divide", Count: 2},
+	"&": {Command: vm.CommandAnd},
+	"|": {Command: vm.CommandOr},
+	"<": {Command: vm.CommandLt},
+	">": {Command: vm.CommandGt},
+	"=": {Command: vm.CommandEq},
+}
+
+// CompileFile parses the Jack class read from r and compiles it to VM code.
+func CompileFile(name string, r io.Reader) (prog vm.Program, err error) {
+	var src []byte
+	if src, err = io.ReadAll(r); err != nil {
+		return
+	}
+
+	var class *Class
+	if class, err = ParseFile(name, bytes.NewReader(src)); err != nil {
+		return
+	}
+
+	c := &compiler{name: name, lines: strings.Split(string(src), "\n")}
+	return c.compile(class)
+}
+
+// Compile compiles the class to VM code.
+func (class *Class) Compile() (prog vm.Program, err error) {
+	return (&compiler{}).compile(class)
+}
+
+func (c *compiler) error(pos Pos, err error) diag.Diagnostic {
+	d := diag.Diagnostic{File: c.name, Line: pos.Line, Column: pos.Column, Err: err}
+	if pos.Line > 0 && pos.Line <= len(c.lines) {
+		d.Source = strings.TrimRight(c.lines[pos.Line-1], "\r")
+	}
+	return d
+}
+
+func (c *compiler) emit(stmts ...vm.Statement) {
+	c.prog = append(c.prog, stmts...)
+}
+
+func (c *compiler) push(seg vm.Segment, index int16) {
+	c.emit(vm.Statement{Command: vm.CommandPush, Segment: seg, Index: index})
+}
+
+func (c *compiler) pop(seg vm.Segment, index int16) {
+	c.emit(vm.Statement{Command: vm.CommandPop, Segment: seg, Index: index})
+}
+
+func (c *compiler) call(function string, count int) {
+	c.emit(vm.Statement{Command: vm.CommandCall, Function: function, Count: int16(count)})
+}
+
+func (c *compiler) label(prefix string) string {
+	label := prefix + "." + strconv.Itoa(c.labels)
+	c.labels += 1
+	return label
+}
+
+func (c *compiler) compile(class *Class) (prog vm.Program, err error) {
+	c.class = class
+	c.classSyms = NewSymbolTable(nil)
+	c.subroutines = map[string]SubroutineDec{}
+
+	for _, dec := range class.Vars {
+		seg := vm.SegmentStatic
+		if dec.Kind == KeywordField {
+			seg = vm.SegmentThis
+		}
+		for _, name := range dec.Names {
+			if !c.classSyms.Define(name, dec.Type, seg) {
+				return nil, c.error(dec.Pos, ErrIdentifierRedeclared{name: name})
+			}
+		}
+	}
+	for _, dec := range class.Subroutines {
+		if _, ok := c.subroutines[dec.Name]; ok {
+			return nil, c.error(dec.Pos, ErrIdentifierRedeclared{name: dec.Name})
+		}
+		c.subroutines[dec.Name] = dec
+	}
+
+	for _, dec := range class.Subroutines {
+		if err = c.subroutineDec(dec); err != nil {
+			return
+		}
+	}
+	return c.prog, nil
+}
+
+func (c *compiler) subroutineDec(dec SubroutineDec) (err error) {
+	c.subroutine = dec
+	c.syms = NewSymbolTable(c.classSyms)
+	c.labels = 0
+
+	if dec.Kind == KeywordMethod {
+		c.syms.Define(KeywordThis, c.class.Name, vm.SegmentArgument)
+	}
+	for _, param := range dec.Params {
+		if !c.syms.Define(param.Name, param.Type, vm.SegmentArgument) {
+			return c.error(param.Pos, ErrIdentifierRedeclared{name: param.Name})
+		}
+	}
+	for _, vars := range dec.Vars {
+		for _, name := range vars.Names {
+			if !c.syms.Define(name, vars.Type, vm.SegmentLocal) {
+				return c.error(vars.Pos, ErrIdentifierRedeclared{name: name})
+			}
+		}
+	}
+
+	c.emit(vm.Statement{
+		Command:  vm.CommandFunction,
+		Function: c.class.Name + "." + dec.Name,
+		Count:    c.syms.Counts[vm.SegmentLocal],
+	})
+	switch dec.Kind {
+	case KeywordConstructor:
+		c.push(vm.SegmentConstant, c.classSyms.Counts[vm.SegmentThis])
+		c.call("Memory.alloc", 1)
+		c.pop(vm.SegmentPointer, 0)
+	case KeywordMethod:
+		c.push(vm.SegmentArgument, 0)
+		c.pop(vm.SegmentPointer, 0)
+	}
+
+	return c.statements(dec.Statements)
+}
+
+// lookup finds the variable name, which must be reachable from the current
+// subroutine.
+func (c *compiler) lookup(pos Pos, name string) (sym Symbol, err error) {
+	var ok bool
+	if sym, ok = c.syms.Lookup(name); !ok {
+		return sym, c.error(pos, ErrIdentifierUndefined{name: name})
+	}
+	if sym.Segment == vm.SegmentThis && c.subroutine.Kind == KeywordFunction {
+		return sym, c.error(pos, ErrThisInFunction{name: name})
+	}
+	return
+}
+
+func (c *compiler) statements(stmts []Statement) (err error) {
+	for _, stmt := range stmts {
+		switch stmt := stmt.(type) {
+		case *LetStatement:
+			err = c.letStatement(stmt)
+		case *IfStatement:
+			err = c.ifStatement(stmt)
+		case *WhileStatement:
+			err = c.whileStatement(stmt)
+		case *DoStatement:
+			if err = c.subroutineCall(&stmt.Call); err == nil {
+				c.pop(vm.SegmentTemp, 0)
+			}
+		case *ReturnStatement:
+			if stmt.Value != nil {
+				err = c.expression(*stmt.Value)
+			} else {
+				c.push(vm.SegmentConstant, 0)
+			}
+			c.emit(vm.Statement{Command: vm.CommandReturn})
+		}
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (c *compiler) letStatement(stmt *LetStatement) (err error) {
+	var sym Symbol
+	if sym, err = c.lookup(stmt.Pos, stmt.Name); err != nil {
+		return
+	}
+
+	if stmt.Index == nil {
+		if err = c.expression(stmt.Value); err != nil {
+			return
+		}
+		c.pop(sym.Segment, sym.Index)
+		return
+	}
+
+	// The value is evaluated before THAT is set, as it may index arrays too.
+	c.push(sym.Segment, sym.Index)
+	if err = c.expression(*stmt.Index); err != nil {
+		return
+	}
+	c.emit(vm.Statement{Command: vm.CommandAdd})
+	if err = c.expression(stmt.Value); err != nil {
+		return
+	}
+	c.pop(vm.SegmentTemp, 0)
+	c.pop(vm.SegmentPointer, 1)
+	c.push(vm.SegmentTemp, 0)
+	c.pop(vm.SegmentThat, 0)
+	return
+}
+
+func (c *compiler) ifStatement(stmt *IfStatement) (err error) {
+	elseLabel, endLabel := c.label("IF_ELSE"), c.label("IF_END")
+
+	if err = c.expression(stmt.Cond); err != nil {
+		return
+	}
+	c.emit(
+		vm.Statement{Command: vm.CommandNot},
+		vm.Statement{Command: vm.CommandIfGoto, Label: elseLabel},
+	)
+	if err = c.statements(stmt.Then); err != nil {
+		return
+	}
+	if stmt.Else == nil {
+		c.emit(vm.Statement{Command: vm.CommandLabel, Label: elseLabel})
+		return
+	}
+
+	c.emit(
+		vm.Statement{Command: vm.CommandGoto, Label: endLabel},
+		vm.Statement{Command: vm.CommandLabel, Label: elseLabel},
+	)
+	if err = c.statements(stmt.Else); err != nil {
+		return
+	}
+	c.emit(vm.Statement{Command: vm.CommandLabel, Label: endLabel})
+	return
+}
+
+func (c *compiler) whileStatement(stmt *WhileStatement) (err error) {
+	loopLabel, endLabel := c.label("WHILE_EXP"), c.label("WHILE_END")
+
+	c.emit(vm.Statement{Command: vm.CommandLabel, Label: loopLabel})
+	if err = c.expression(stmt.Cond); err != nil {
+		return
+	}
+	c.emit(
+		vm.Statement{Command: vm.CommandNot},
+		vm.Statement{Command: vm.CommandIfGoto, Label: endLabel},
+	)
+	if err = c.statements(stmt.Body); err != nil {
+		return
+	}
+	c.emit(
+		vm.Statement{Command: vm.CommandGoto, Label: loopLabel},
+		vm.Statement{Command: vm.CommandLabel, Label: endLabel},
+	)
+	return
+}
+
+func (c *compiler) expression(expr Expression) (err error) {
+	if err = c.term(expr.Term); err != nil {
+		return
+	}
+	for _, op := range expr.Ops {
+		if err = c.term(op.Term); err != nil {
+			return
+		}
+		c.emit(binaryOpToStatement[op.Op])
+	}
+	return
+}
+
+func (c *compiler) term(term Term) (err error) {
+	switch term := term.(type) {
+	case *IntegerConstant:
+		c.push(vm.SegmentConstant, int16(term.Value))
+	case *StringConstant:
+		c.push(vm.SegmentConstant, int16(len(term.Value)))
+		c.call("String.new", 1)
+		for _, char := range []byte(term.Value) {
+			c.push(vm.SegmentConstant, int16(char))
+			c.call("String.appendChar", 2)
+		}
+	case *KeywordConstant:
+		switch term.Value {
+		case KeywordTrue:
+			c.push(vm.SegmentConstant, 0)
+			c.emit(vm.Statement{Command: vm.CommandNot})
+		case KeywordFalse, KeywordNull:
+			c.push(vm.SegmentConstant, 0)
+		case KeywordThis:
+			if c.subroutine.Kind == KeywordFunction {
+				return c.error(term.Pos, ErrThisInFunction{name: KeywordThis})
+			}
+			c.push(vm.SegmentPointer, 0)
+		}
+	case *VarTerm:
+		var sym Symbol
+		if sym, err = c.lookup(term.Pos, term.Name); err != nil {
+			return
+		}
+		c.push(sym.Segment, sym.Index)
+	case *IndexTerm:
+		var sym Symbol
+		if sym, err = c.lookup(term.Pos, term.Name); err != nil {
+			return
+		}
+		c.push(sym.Segment, sym.Index)
+		if err = c.expression(term.Index); err != nil {
+			return
+		}
+		c.emit(vm.Statement{Command: vm.CommandAdd})
+		c.pop(vm.SegmentPointer, 1)
+		c.push(vm.SegmentThat, 0)
+	case *SubroutineCall:
+		err = c.subroutineCall(term)
+	case *ParenTerm:
+		err = c.expression(term.Expr)
+	case *UnaryTerm:
+		if err = c.term(term.Term); err != nil {
+			return
+		}
+		if term.Op == "-" {
+			c.emit(vm.Statement{Command: vm.CommandNeg})
+		} else {
+			c.emit(vm.Statement{Command: vm.CommandNot})
+		}
+	}
+	return
+}
+
+// subroutineCall compiles a call. Methods receive their object as the first
+// argument: the current object for unqualified calls, or the variable named
+// by the receiver. Other receivers are class names.
+func (c *compiler) subroutineCall(call *SubroutineCall) (err error) {
+	function := call.Receiver + "." + call.Name
+	count := len(call.Args)
+
+	switch sym, ok := c.syms.Lookup(call.Receiver); {
+	case call.Receiver == "":
+		dec, ok := c.subroutines[call.Name]
+		if !ok {
+			return c.error(call.Pos, ErrSubroutineUndefined{name: call.Name})
+		}
+		function = c.class.Name + "." + call.Name
+		if dec.Kind == KeywordMethod {
+			if c.subroutine.Kind == KeywordFunction {
+				return c.error(call.Pos, ErrThisInFunction{name: call.Name})
+			}
+			c.push(vm.SegmentPointer, 0)
+			count += 1
+		}
+	case ok:
+		if sym, err = c.lookup(call.Pos, call.Receiver); err != nil {
+			return
+		}
+		function = sym.Type + "." + call.Name
+		c.push(sym.Segment, sym.Index)
+		count += 1
+	}
+
+	for _, arg := range call.Args {
+		if err = c.expression(arg); err != nil {
+			return
+		}
+	}
+	c.call(function, count)
+	return
+}
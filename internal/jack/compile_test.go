@@ -0,0 +1,146 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jack
+
+import (
+	"bytes"
+	"hack/internal/diag"
+	"hack/internal/vm"
+	"hack/internal/vme"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// run compiles the classes of dir and runs them in the VM emulator.
+func run(t *testing.T, dir string, setup func(m *vme.Machine)) (m *vme.Machine, out string) {
+	paths, _ := filepath.Glob(filepath.Join(dir, "*.jack"))
+	assert.NotEmpty(t, paths)
+
+	m = vme.New()
+	for _, jackPath := range paths {
+		file, err := os.Open(jackPath)
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+		prog, err := CompileFile(jackPath, file)
+		file.Close()
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+		m.Load(strings.TrimSuffix(filepath.Base(jackPath), ".jack"), prog)
+	}
+	if !assert.Nil(t, m.Link()) {
+		t.FailNow()
+	}
+
+	var buf bytes.Buffer
+	m.Stdout = &buf
+	if setup != nil {
+		setup(m)
+	}
+	assert.Nil(t, m.Run(1_000_000))
+	assert.True(t, m.Halted)
+	return m, buf.String()
+}
+
+func TestCompile(t *testing.T) {
+	class, err := ParseString(`
+class Point {
+	field int x;
+	static int count;
+
+	constructor Point new(int ax) {
+		let x = ax;
+		return this;
+	}
+
+	method int getX() {
+		if (x < 0) { return -x; }
+		return x;
+	}
+}
+`)
+	assert.Nil(t, err)
+
+	prog, err := class.Compile()
+	assert.Nil(t, err)
+
+	str, err := vm.FormatString(prog)
+	assert.Nil(t, err)
+	assert.Equal(t, strings.Trim(`
+function Point.new 0
+push constant 1
+call Memory.alloc 1
+pop pointer 0
+push argument 0
+pop this 0
+push pointer 0
+return
+function Point.getX 0
+push argument 0
+pop pointer 0
+push this 0
+push constant 0
+lt
+not
+if-goto IF_ELSE.0
+push this 0
+neg
+return
+label IF_ELSE.0
+push this 0
+return
+`, "\n"), str)
+}
+
+func TestCompileError(t *testing.T) {
+	for _, test := range []struct {
+		src  string
+		err  error
+		line int
+	}{
+		{src: "class A {\n function void f() {\n  let y = 1;\n  return;\n }\n}", err: ErrIdentifierUndefined{name: "y"}, line: 3},
+		{src: "class A {\n field int x;\n function int f() {\n  return x;\n }\n}", err: ErrThisInFunction{name: "x"}, line: 4},
+		{src: "class A {\n function void f() {\n  do g();\n  return;\n }\n}", err: ErrSubroutineUndefined{name: "g"}, line: 3},
+		{src: "class A {\n function void f(int a) {\n  var int a;\n  return;\n }\n}", err: ErrIdentifierRedeclared{name: "a"}, line: 3},
+	} {
+		_, err := CompileFile("A.jack", strings.NewReader(test.src))
+		d, ok := err.(diag.Diagnostic)
+		if assert.True(t, ok, test.src) {
+			assert.Equal(t, test.err, d.Err)
+			assert.Equal(t, test.line, d.Line)
+			assert.NotEmpty(t, d.Source)
+		}
+	}
+}
+
+func TestCompileSeven(t *testing.T) {
+	_, out := run(t, "../../projects/11/Seven", nil)
+	assert.Equal(t, "7", out)
+}
+
+func TestCompileConvertToBin(t *testing.T) {
+	m, _ := run(t, "../../projects/11/ConvertToBin", func(m *vme.Machine) {
+		m.RAM[8000] = 0b1011
+	})
+	for i, bit := range []int16{1, 1, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0} {
+		assert.Equal(t, bit, m.RAM[8001+i], i)
+	}
+}
+
+func TestCompileComplexArrays(t *testing.T) {
+	_, out := run(t, "../../projects/11/ComplexArrays", nil)
+
+	results := regexp.MustCompile(`expected result: (-?\d+); actual result: (-?\d+)`).FindAllStringSubmatch(out, -1)
+	assert.Len(t, results, 5)
+	for _, result := range results {
+		assert.Equal(t, result[1], result[2], result[0])
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jack
+
+const (
+	TokenKeyword TokenKind = iota
+	TokenSymbol
+	TokenIntegerConstant
+	TokenStringConstant
+	TokenIdentifier
+)
+
+const (
+	KeywordClass       = "class"
+	KeywordConstructor = "constructor"
+	KeywordFunction    = "function"
+	KeywordMethod      = "method"
+	KeywordField       = "field"
+	KeywordStatic      = "static"
+	KeywordVar         = "var"
+	KeywordInt         = "int"
+	KeywordChar        = "char"
+	KeywordBoolean     = "boolean"
+	KeywordVoid        = "void"
+	KeywordTrue        = "true"
+	KeywordFalse       = "false"
+	KeywordNull        = "null"
+	KeywordThis        = "this"
+	KeywordLet         = "let"
+	KeywordDo          = "do"
+	KeywordIf          = "if"
+	KeywordElse        = "else"
+	KeywordWhile       = "while"
+	KeywordReturn      = "return"
+)
+
+const (
+	Symbols   = "{}()[].,;+-*/&|<>=~"
+	BinaryOps = "+-*/&|<>="
+
+	// MaxInteger is the largest integer constant of the language.
+	MaxInteger = 32767
+)
+
+var (
+	Keywords = map[string]bool{
+		KeywordClass:       true,
+		KeywordConstructor: true,
+		KeywordFunction:    true,
+		KeywordMethod:      true,
+		KeywordField:       true,
+		KeywordStatic:      true,
+		KeywordVar:         true,
+		KeywordInt:         true,
+		KeywordChar:        true,
+		KeywordBoolean:     true,
+		KeywordVoid:        true,
+		KeywordTrue:        true,
+		KeywordFalse:       true,
+		KeywordNull:        true,
+		KeywordThis:        true,
+		KeywordLet:         true,
+		KeywordDo:          true,
+		KeywordIf:          true,
+		KeywordElse:        true,
+		KeywordWhile:       true,
+		KeywordReturn:      true,
+	}
+
+	TokenKindToString = map[TokenKind]string{
+		TokenKeyword:         "keyword",
+		TokenSymbol:          "symbol",
+		TokenIntegerConstant: "integerConstant",
+		TokenStringConstant:  "stringConstant",
+		TokenIdentifier:      "identifier",
+	}
+)
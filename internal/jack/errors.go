@@ -0,0 +1,75 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jack
+
+import (
+	"errors"
+	"strconv"
+)
+
+var (
+	ErrCommentUnterminated = errors.New("comment not terminated")
+	ErrStringUnterminated  = errors.New("string constant not terminated")
+	ErrUnexpectedEOF       = errors.New("unexpected end of file")
+)
+
+type (
+	ErrCharacterInvalid struct {
+		char byte
+	}
+
+	ErrIntegerInvalid struct {
+		integer string
+	}
+
+	ErrUnexpectedToken struct {
+		token    string
+		expected string
+	}
+
+	ErrIdentifierUndefined struct {
+		name string
+	}
+
+	ErrIdentifierRedeclared struct {
+		name string
+	}
+
+	ErrSubroutineUndefined struct {
+		name string
+	}
+
+	ErrThisInFunction struct {
+		name string
+	}
+)
+
+func (err ErrCharacterInvalid) Error() string {
+	return "invalid character: " + strconv.QuoteRune(rune(err.char))
+}
+
+func (err ErrIntegerInvalid) Error() string {
+	return "invalid integer constant: " + err.integer
+}
+
+func (err ErrUnexpectedToken) Error() string {
+	return "unexpected " + strconv.Quote(err.token) + ", expected " + err.expected
+}
+
+func (err ErrIdentifierUndefined) Error() string {
+	return "undefined: " + err.name
+}
+
+func (err ErrIdentifierRedeclared) Error() string {
+	return err.name + " redeclared"
+}
+
+func (err ErrSubroutineUndefined) Error() string {
+	return "undefined subroutine: " + err.name
+}
+
+func (err ErrThisInFunction) Error() string {
+	return err.name + " used in a function"
+}
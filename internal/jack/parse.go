@@ -0,0 +1,471 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jack
+
+import (
+	"bytes"
+	"hack/internal/diag"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type (
+	parser struct {
+		name   string
+		lines  []string
+		tokens []Token
+		pos    int
+	}
+)
+
+func ParseString(str string) (class *Class, err error) {
+	return Parse(strings.NewReader(str))
+}
+
+func Parse(r io.Reader) (class *Class, err error) {
+	return ParseFile("", r)
+}
+
+// ParseFile parses the Jack class read from r.
+func ParseFile(name string, r io.Reader) (class *Class, err error) {
+	var src []byte
+	if src, err = io.ReadAll(r); err != nil {
+		return
+	}
+
+	p := &parser{name: name, lines: strings.Split(string(src), "\n")}
+	if p.tokens, err = TokenizeFile(name, bytes.NewReader(src)); err != nil {
+		return
+	}
+
+	if class, err = p.class(); err != nil {
+		return
+	}
+	if p.pos < len(p.tokens) {
+		return nil, p.unexpected("end of file")
+	}
+	return
+}
+
+func (p *parser) peek() (tok Token, ok bool) {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos], true
+	}
+	return
+}
+
+// at reports whether the next token is the keyword or symbol value.
+func (p *parser) at(value string) bool {
+	tok, ok := p.peek()
+	return ok && tok.Is(value)
+}
+
+func (p *parser) position() Pos {
+	if tok, ok := p.peek(); ok {
+		return Pos{Line: tok.Line, Column: tok.Column}
+	}
+	return Pos{}
+}
+
+func (p *parser) unexpected(expected string) error {
+	tok, ok := p.peek()
+	if !ok {
+		line := len(p.lines)
+		return diag.Diagnostic{File: p.name, Line: line, Column: len(p.lines[line-1]) + 1, Err: ErrUnexpectedEOF}
+	}
+	return diag.Diagnostic{
+		File:   p.name,
+		Line:   tok.Line,
+		Column: tok.Column,
+		Source: strings.TrimRight(p.lines[tok.Line-1], "\r"),
+		Err:    ErrUnexpectedToken{token: tok.Value, expected: expected},
+	}
+}
+
+// expect consumes the keyword or symbol value.
+func (p *parser) expect(value string) (err error) {
+	if !p.at(value) {
+		return p.unexpected(strconv.Quote(value))
+	}
+	p.pos += 1
+	return
+}
+
+// oneOf consumes one of the keywords or symbols values.
+func (p *parser) oneOf(values ...string) (value string, err error) {
+	for _, value = range values {
+		if p.at(value) {
+			p.pos += 1
+			return
+		}
+	}
+	return "", p.unexpected(strings.Join(values, " or "))
+}
+
+func (p *parser) identifier() (name string, err error) {
+	tok, ok := p.peek()
+	if !ok || tok.Kind != TokenIdentifier {
+		return "", p.unexpected("identifier")
+	}
+	p.pos += 1
+	return tok.Value, nil
+}
+
+// typ parses a type, also accepting void if void is set.
+func (p *parser) typ(void bool) (typ string, err error) {
+	tok, ok := p.peek()
+	switch {
+	case ok && tok.Kind == TokenIdentifier,
+		ok && (tok.Is(KeywordInt) || tok.Is(KeywordChar) || tok.Is(KeywordBoolean)),
+		ok && void && tok.Is(KeywordVoid):
+		p.pos += 1
+		return tok.Value, nil
+	}
+	return "", p.unexpected("type")
+}
+
+// names parses a comma separated list of identifiers ending with a semicolon.
+func (p *parser) names() (names []string, err error) {
+	var name string
+	for {
+		if name, err = p.identifier(); err != nil {
+			return
+		}
+		names = append(names, name)
+		if !p.at(",") {
+			break
+		}
+		p.pos += 1
+	}
+	err = p.expect(";")
+	return
+}
+
+func (p *parser) class() (class *Class, err error) {
+	class = &Class{Pos: p.position()}
+	if err = p.expect(KeywordClass); err != nil {
+		return
+	}
+	if class.Name, err = p.identifier(); err != nil {
+		return
+	}
+	if err = p.expect("{"); err != nil {
+		return
+	}
+
+	for p.at(KeywordStatic) || p.at(KeywordField) {
+		dec := ClassVarDec{Pos: p.position()}
+		if dec.Kind, err = p.oneOf(KeywordStatic, KeywordField); err != nil {
+			return
+		}
+		if dec.Type, err = p.typ(false); err != nil {
+			return
+		}
+		if dec.Names, err = p.names(); err != nil {
+			return
+		}
+		class.Vars = append(class.Vars, dec)
+	}
+
+	for !p.at("}") {
+		var dec SubroutineDec
+		if dec, err = p.subroutineDec(); err != nil {
+			return
+		}
+		class.Subroutines = append(class.Subroutines, dec)
+	}
+	err = p.expect("}")
+	return
+}
+
+func (p *parser) subroutineDec() (dec SubroutineDec, err error) {
+	dec.Pos = p.position()
+	if dec.Kind, err = p.oneOf(KeywordConstructor, KeywordFunction, KeywordMethod); err != nil {
+		return
+	}
+	if dec.ReturnType, err = p.typ(true); err != nil {
+		return
+	}
+	if dec.Name, err = p.identifier(); err != nil {
+		return
+	}
+
+	if err = p.expect("("); err != nil {
+		return
+	}
+	for !p.at(")") {
+		if len(dec.Params) > 0 {
+			if err = p.expect(","); err != nil {
+				return
+			}
+		}
+		param := Parameter{Pos: p.position()}
+		if param.Type, err = p.typ(false); err != nil {
+			return
+		}
+		if param.Name, err = p.identifier(); err != nil {
+			return
+		}
+		dec.Params = append(dec.Params, param)
+	}
+	p.pos += 1
+
+	if err = p.expect("{"); err != nil {
+		return
+	}
+	for p.at(KeywordVar) {
+		vars := VarDec{Pos: p.position()}
+		p.pos += 1
+		if vars.Type, err = p.typ(false); err != nil {
+			return
+		}
+		if vars.Names, err = p.names(); err != nil {
+			return
+		}
+		dec.Vars = append(dec.Vars, vars)
+	}
+	if dec.Statements, err = p.statements(); err != nil {
+		return
+	}
+	err = p.expect("}")
+	return
+}
+
+func (p *parser) statements() (stmts []Statement, err error) {
+	for !p.at("}") {
+		var stmt Statement
+		switch pos := p.position(); {
+		case p.at(KeywordLet):
+			stmt, err = p.letStatement(pos)
+		case p.at(KeywordIf):
+			stmt, err = p.ifStatement(pos)
+		case p.at(KeywordWhile):
+			stmt, err = p.whileStatement(pos)
+		case p.at(KeywordDo):
+			stmt, err = p.doStatement(pos)
+		case p.at(KeywordReturn):
+			stmt, err = p.returnStatement(pos)
+		default:
+			err = p.unexpected("statement")
+		}
+		if err != nil {
+			return
+		}
+		stmts = append(stmts, stmt)
+	}
+	return
+}
+
+// block parses statements enclosed in braces.
+func (p *parser) block() (stmts []Statement, err error) {
+	if err = p.expect("{"); err != nil {
+		return
+	}
+	if stmts, err = p.statements(); err != nil {
+		return
+	}
+	if stmts == nil {
+		stmts = []Statement{}
+	}
+	err = p.expect("}")
+	return
+}
+
+// condition parses an expression enclosed in parentheses.
+func (p *parser) condition() (cond Expression, err error) {
+	if err = p.expect("("); err != nil {
+		return
+	}
+	if cond, err = p.expression(); err != nil {
+		return
+	}
+	err = p.expect(")")
+	return
+}
+
+func (p *parser) letStatement(pos Pos) (stmt *LetStatement, err error) {
+	stmt = &LetStatement{Pos: pos}
+	p.pos += 1
+	if stmt.Name, err = p.identifier(); err != nil {
+		return
+	}
+	if p.at("[") {
+		p.pos += 1
+		var index Expression
+		if index, err = p.expression(); err != nil {
+			return
+		}
+		stmt.Index = &index
+		if err = p.expect("]"); err != nil {
+			return
+		}
+	}
+	if err = p.expect("="); err != nil {
+		return
+	}
+	if stmt.Value, err = p.expression(); err != nil {
+		return
+	}
+	err = p.expect(";")
+	return
+}
+
+func (p *parser) ifStatement(pos Pos) (stmt *IfStatement, err error) {
+	stmt = &IfStatement{Pos: pos}
+	p.pos += 1
+	if stmt.Cond, err = p.condition(); err != nil {
+		return
+	}
+	if stmt.Then, err = p.block(); err != nil {
+		return
+	}
+	if p.at(KeywordElse) {
+		p.pos += 1
+		stmt.Else, err = p.block()
+	}
+	return
+}
+
+func (p *parser) whileStatement(pos Pos) (stmt *WhileStatement, err error) {
+	stmt = &WhileStatement{Pos: pos}
+	p.pos += 1
+	if stmt.Cond, err = p.condition(); err != nil {
+		return
+	}
+	stmt.Body, err = p.block()
+	return
+}
+
+func (p *parser) doStatement(pos Pos) (stmt *DoStatement, err error) {
+	stmt = &DoStatement{Pos: pos}
+	p.pos += 1
+
+	callPos := p.position()
+	var name string
+	if name, err = p.identifier(); err != nil {
+		return
+	}
+	var call *SubroutineCall
+	if call, err = p.subroutineCall(callPos, name); err != nil {
+		return
+	}
+	stmt.Call = *call
+	err = p.expect(";")
+	return
+}
+
+func (p *parser) returnStatement(pos Pos) (stmt *ReturnStatement, err error) {
+	stmt = &ReturnStatement{Pos: pos}
+	p.pos += 1
+	if !p.at(";") {
+		var value Expression
+		if value, err = p.expression(); err != nil {
+			return
+		}
+		stmt.Value = &value
+	}
+	err = p.expect(";")
+	return
+}
+
+func (p *parser) expression() (expr Expression, err error) {
+	if expr.Term, err = p.term(); err != nil {
+		return
+	}
+	for {
+		tok, ok := p.peek()
+		if !ok || tok.Kind != TokenSymbol || !strings.Contains(BinaryOps, tok.Value) {
+			return
+		}
+		p.pos += 1
+
+		op := BinaryOp{Op: tok.Value}
+		if op.Term, err = p.term(); err != nil {
+			return
+		}
+		expr.Ops = append(expr.Ops, op)
+	}
+}
+
+func (p *parser) term() (term Term, err error) {
+	pos := p.position()
+	tok, ok := p.peek()
+	if !ok {
+		return nil, p.unexpected("term")
+	}
+
+	switch {
+	case tok.Kind == TokenIntegerConstant:
+		p.pos += 1
+		value, _ := strconv.Atoi(tok.Value)
+		return &IntegerConstant{Pos: pos, Value: value}, nil
+	case tok.Kind == TokenStringConstant:
+		p.pos += 1
+		return &StringConstant{Pos: pos, Value: tok.Value}, nil
+	case tok.Is(KeywordTrue) || tok.Is(KeywordFalse) || tok.Is(KeywordNull) || tok.Is(KeywordThis):
+		p.pos += 1
+		return &KeywordConstant{Pos: pos, Value: tok.Value}, nil
+	case tok.Is("("):
+		p.pos += 1
+		paren := &ParenTerm{Pos: pos}
+		if paren.Expr, err = p.expression(); err != nil {
+			return
+		}
+		return paren, p.expect(")")
+	case tok.Is("-") || tok.Is("~"):
+		p.pos += 1
+		unary := &UnaryTerm{Pos: pos, Op: tok.Value}
+		if unary.Term, err = p.term(); err != nil {
+			return
+		}
+		return unary, nil
+	case tok.Kind == TokenIdentifier:
+		p.pos += 1
+		switch {
+		case p.at("["):
+			p.pos += 1
+			index := &IndexTerm{Pos: pos, Name: tok.Value}
+			if index.Index, err = p.expression(); err != nil {
+				return
+			}
+			return index, p.expect("]")
+		case p.at("(") || p.at("."):
+			return p.subroutineCall(pos, tok.Value)
+		}
+		return &VarTerm{Pos: pos, Name: tok.Value}, nil
+	}
+	return nil, p.unexpected("term")
+}
+
+// subroutineCall parses the rest of a call starting with the identifier name.
+func (p *parser) subroutineCall(pos Pos, name string) (call *SubroutineCall, err error) {
+	call = &SubroutineCall{Pos: pos, Name: name}
+	if p.at(".") {
+		p.pos += 1
+		call.Receiver = name
+		if call.Name, err = p.identifier(); err != nil {
+			return
+		}
+	}
+
+	if err = p.expect("("); err != nil {
+		return
+	}
+	for !p.at(")") {
+		if len(call.Args) > 0 {
+			if err = p.expect(","); err != nil {
+				return
+			}
+		}
+		var arg Expression
+		if arg, err = p.expression(); err != nil {
+			return
+		}
+		call.Args = append(call.Args, arg)
+	}
+	p.pos += 1
+	return
+}
@@ -0,0 +1,102 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jack
+
+import (
+	"hack/internal/diag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	class, err := ParseString(`
+class Foo {
+	field int x, y;
+
+	method int get(int i) {
+		var Array a;
+		let a[i] = -x + (y * 2);
+		if (~(i < 0)) { return a[i]; } else { }
+		do Output.printInt(i);
+		return this;
+	}
+}
+`)
+	assert.Nil(t, err)
+	assert.Equal(t, "Foo", class.Name)
+	assert.Equal(t, []ClassVarDec{{Pos: Pos{Line: 3, Column: 2}, Kind: "field", Type: "int", Names: []string{"x", "y"}}}, class.Vars)
+
+	if !assert.Len(t, class.Subroutines, 1) {
+		return
+	}
+	dec := class.Subroutines[0]
+	assert.Equal(t, "method", dec.Kind)
+	assert.Equal(t, []Parameter{{Pos: Pos{Line: 5, Column: 17}, Type: "int", Name: "i"}}, dec.Params)
+	assert.Len(t, dec.Statements, 4)
+
+	let := dec.Statements[0].(*LetStatement)
+	assert.Equal(t, &VarTerm{Pos: Pos{Line: 7, Column: 9}, Name: "i"}, let.Index.Term)
+	assert.Equal(t, &UnaryTerm{Pos: Pos{Line: 7, Column: 14}, Op: "-", Term: &VarTerm{Pos: Pos{Line: 7, Column: 15}, Name: "x"}}, let.Value.Term)
+	assert.Equal(t, "+", let.Value.Ops[0].Op)
+
+	ifStmt := dec.Statements[1].(*IfStatement)
+	assert.NotNil(t, ifStmt.Else)
+	assert.Empty(t, ifStmt.Else)
+
+	do := dec.Statements[2].(*DoStatement)
+	assert.Equal(t, "Output", do.Call.Receiver)
+	assert.Equal(t, "printInt", do.Call.Name)
+}
+
+func TestParseError(t *testing.T) {
+	for _, test := range []struct {
+		src    string
+		err    error
+		line   int
+		column int
+	}{
+		{src: "class Foo { field int; }", err: ErrUnexpectedToken{token: ";", expected: "identifier"}, line: 1, column: 22},
+		{src: "class Foo {\n  function void f() { let x = ; }\n}", err: ErrUnexpectedToken{token: ";", expected: "term"}, line: 2, column: 31},
+		{src: "class Foo {\n  function void f() { return; }", err: ErrUnexpectedEOF, line: 2, column: 32},
+		{src: "class Foo { } class", err: ErrUnexpectedToken{token: "class", expected: "end of file"}, line: 1, column: 15},
+	} {
+		_, err := ParseString(test.src)
+		d, ok := err.(diag.Diagnostic)
+		if assert.True(t, ok, test.src) {
+			assert.Equal(t, test.err, d.Err)
+			assert.Equal(t, test.line, d.Line)
+			assert.Equal(t, test.column, d.Column)
+		}
+	}
+}
+
+func TestFormatXML(t *testing.T) {
+	paths, _ := filepath.Glob("../../projects/10/*/*.jack")
+	assert.NotEmpty(t, paths)
+
+	for _, jackPath := range paths {
+		t.Run(jackPath, func(t *testing.T) {
+			file, err := os.Open(jackPath)
+			assert.Nil(t, err)
+			defer file.Close()
+
+			class, err := ParseFile(jackPath, file)
+			if !assert.Nil(t, err) {
+				return
+			}
+
+			var out strings.Builder
+			assert.Nil(t, class.FormatXML(&out))
+
+			cmp, err := os.ReadFile(strings.TrimSuffix(jackPath, ".jack") + ".xml")
+			assert.Nil(t, err)
+			assert.Equal(t, strings.ReplaceAll(string(cmp), "\r", ""), out.String())
+		})
+	}
+}
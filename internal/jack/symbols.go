@@ -0,0 +1,53 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jack
+
+import "hack/internal/vm"
+
+type (
+	// Symbol is a variable stored at Index of Segment.
+	Symbol struct {
+		Segment vm.Segment
+		Type    string
+		Index   int16
+	}
+
+	// SymbolTable holds the variables of a class, or of a subroutine with the
+	// class table as parent.
+	SymbolTable struct {
+		Parent  *SymbolTable
+		Symbols map[string]Symbol
+		Counts  map[vm.Segment]int16
+	}
+)
+
+func NewSymbolTable(parent *SymbolTable) *SymbolTable {
+	return &SymbolTable{
+		Parent:  parent,
+		Symbols: map[string]Symbol{},
+		Counts:  map[vm.Segment]int16{},
+	}
+}
+
+// Define adds name to the next index of seg. It reports false if name is
+// already defined in this table.
+func (syms *SymbolTable) Define(name, typ string, seg vm.Segment) bool {
+	if _, ok := syms.Symbols[name]; ok {
+		return false
+	}
+	syms.Symbols[name] = Symbol{Segment: seg, Type: typ, Index: syms.Counts[seg]}
+	syms.Counts[seg] += 1
+	return true
+}
+
+// Lookup finds name in the table or its parents.
+func (syms *SymbolTable) Lookup(name string) (sym Symbol, ok bool) {
+	for ; syms != nil; syms = syms.Parent {
+		if sym, ok = syms.Symbols[name]; ok {
+			return
+		}
+	}
+	return
+}
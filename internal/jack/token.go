@@ -0,0 +1,31 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jack
+
+import "strconv"
+
+type (
+	TokenKind int
+
+	Token struct {
+		Kind  TokenKind
+		Value string
+
+		Line   int
+		Column int
+	}
+)
+
+func (kind TokenKind) String() string {
+	if str, ok := TokenKindToString[kind]; ok {
+		return str
+	}
+	return "TokenKind(" + strconv.Itoa(int(kind)) + ")"
+}
+
+// Is reports whether the token is the keyword or symbol value.
+func (tok Token) Is(value string) bool {
+	return (tok.Kind == TokenKeyword || tok.Kind == TokenSymbol) && tok.Value == value
+}
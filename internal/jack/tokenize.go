@@ -0,0 +1,172 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jack
+
+import (
+	"hack/internal/diag"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type (
+	tokenizer struct {
+		name  string
+		src   string
+		lines []string
+
+		pos    int
+		line   int
+		column int
+	}
+)
+
+func TokenizeString(str string) (tokens []Token, err error) {
+	return Tokenize(strings.NewReader(str))
+}
+
+func Tokenize(r io.Reader) (tokens []Token, err error) {
+	return TokenizeFile("", r)
+}
+
+// TokenizeFile splits the Jack source read from r into tokens, skipping
+// white space and comments.
+func TokenizeFile(name string, r io.Reader) (tokens []Token, err error) {
+	var src []byte
+	if src, err = io.ReadAll(r); err != nil {
+		return
+	}
+
+	t := &tokenizer{
+		name:   name,
+		src:    string(src),
+		lines:  strings.Split(string(src), "\n"),
+		line:   1,
+		column: 1,
+	}
+
+	var tok Token
+	var ok bool
+	for {
+		if tok, ok, err = t.next(); err != nil || !ok {
+			return
+		}
+		tokens = append(tokens, tok)
+	}
+}
+
+func (t *tokenizer) peek(offset int) byte {
+	if t.pos+offset < len(t.src) {
+		return t.src[t.pos+offset]
+	}
+	return 0
+}
+
+func (t *tokenizer) advance() {
+	if t.src[t.pos] == '\n' {
+		t.line += 1
+		t.column = 1
+	} else {
+		t.column += 1
+	}
+	t.pos += 1
+}
+
+func (t *tokenizer) error(line, column int, err error) diag.Diagnostic {
+	return diag.Diagnostic{
+		File:   t.name,
+		Line:   line,
+		Column: column,
+		Source: strings.TrimRight(t.lines[line-1], "\r"),
+		Err:    err,
+	}
+}
+
+// skip skips white space and comments.
+func (t *tokenizer) skip() (err error) {
+	for t.pos < len(t.src) {
+		switch char := t.peek(0); {
+		case char == ' ' || char == '\t' || char == '\r' || char == '\n':
+			t.advance()
+		case char == '/' && t.peek(1) == '/':
+			for t.pos < len(t.src) && t.peek(0) != '\n' {
+				t.advance()
+			}
+		case char == '/' && t.peek(1) == '*':
+			line, column := t.line, t.column
+			t.advance()
+			t.advance()
+			for !(t.peek(0) == '*' && t.peek(1) == '/') {
+				if t.pos >= len(t.src) {
+					return t.error(line, column, ErrCommentUnterminated)
+				}
+				t.advance()
+			}
+			t.advance()
+			t.advance()
+		default:
+			return
+		}
+	}
+	return
+}
+
+func (t *tokenizer) next() (tok Token, ok bool, err error) {
+	if err = t.skip(); err != nil || t.pos >= len(t.src) {
+		return
+	}
+
+	tok = Token{Line: t.line, Column: t.column}
+	start := t.pos
+
+	switch char := t.peek(0); {
+	case strings.IndexByte(Symbols, char) >= 0:
+		t.advance()
+		tok.Kind = TokenSymbol
+		tok.Value = t.src[start:t.pos]
+	case isDigit(char):
+		for isDigit(t.peek(0)) {
+			t.advance()
+		}
+		tok.Kind = TokenIntegerConstant
+		tok.Value = t.src[start:t.pos]
+		if value, convErr := strconv.Atoi(tok.Value); convErr != nil || value > MaxInteger {
+			return tok, false, t.error(tok.Line, tok.Column, ErrIntegerInvalid{integer: tok.Value})
+		}
+	case char == '"':
+		t.advance()
+		for t.peek(0) != '"' {
+			if t.pos >= len(t.src) || t.peek(0) == '\n' {
+				return tok, false, t.error(tok.Line, tok.Column, ErrStringUnterminated)
+			}
+			t.advance()
+		}
+		t.advance()
+		tok.Kind = TokenStringConstant
+		tok.Value = t.src[start+1 : t.pos-1]
+	case isLetter(char):
+		for isLetter(t.peek(0)) || isDigit(t.peek(0)) {
+			t.advance()
+		}
+		tok.Value = t.src[start:t.pos]
+		if Keywords[tok.Value] {
+			tok.Kind = TokenKeyword
+		} else {
+			tok.Kind = TokenIdentifier
+		}
+	default:
+		return tok, false, t.error(tok.Line, tok.Column, ErrCharacterInvalid{char: char})
+	}
+
+	return tok, true, nil
+}
+
+func isDigit(char byte) bool {
+	return char >= '0' && char <= '9'
+}
+
+func isLetter(char byte) bool {
+	return char >= 'a' && char <= 'z' || char >= 'A' && char <= 'Z' || char == '_'
+}
@@ -0,0 +1,87 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jack
+
+import (
+	"hack/internal/diag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenize(t *testing.T) {
+	tokens, err := TokenizeString(`
+/** API doc */
+let x = a[1] + "hi there"; // comment
+/* multi
+   line */ do Foo.bar_2();
+`)
+	assert.Nil(t, err)
+	assert.Equal(t, []Token{
+		{Kind: TokenKeyword, Value: "let", Line: 3, Column: 1},
+		{Kind: TokenIdentifier, Value: "x", Line: 3, Column: 5},
+		{Kind: TokenSymbol, Value: "=", Line: 3, Column: 7},
+		{Kind: TokenIdentifier, Value: "a", Line: 3, Column: 9},
+		{Kind: TokenSymbol, Value: "[", Line: 3, Column: 10},
+		{Kind: TokenIntegerConstant, Value: "1", Line: 3, Column: 11},
+		{Kind: TokenSymbol, Value: "]", Line: 3, Column: 12},
+		{Kind: TokenSymbol, Value: "+", Line: 3, Column: 14},
+		{Kind: TokenStringConstant, Value: "hi there", Line: 3, Column: 16},
+		{Kind: TokenSymbol, Value: ";", Line: 3, Column: 26},
+		{Kind: TokenKeyword, Value: "do", Line: 5, Column: 12},
+		{Kind: TokenIdentifier, Value: "Foo", Line: 5, Column: 15},
+		{Kind: TokenSymbol, Value: ".", Line: 5, Column: 18},
+		{Kind: TokenIdentifier, Value: "bar_2", Line: 5, Column: 19},
+		{Kind: TokenSymbol, Value: "(", Line: 5, Column: 24},
+		{Kind: TokenSymbol, Value: ")", Line: 5, Column: 25},
+		{Kind: TokenSymbol, Value: ";", Line: 5, Column: 26},
+	}, tokens)
+}
+
+func TestTokenizeError(t *testing.T) {
+	for _, test := range []struct {
+		src    string
+		err    error
+		column int
+	}{
+		{src: "let x = 32768;", err: ErrIntegerInvalid{integer: "32768"}, column: 9},
+		{src: `let s = "abc;`, err: ErrStringUnterminated, column: 9},
+		{src: "let x = 1; /* oops", err: ErrCommentUnterminated, column: 12},
+		{src: "let x = #;", err: ErrCharacterInvalid{char: '#'}, column: 9},
+	} {
+		_, err := TokenizeString(test.src)
+		d, ok := err.(diag.Diagnostic)
+		if assert.True(t, ok, test.src) {
+			assert.Equal(t, test.err, d.Err)
+			assert.Equal(t, test.column, d.Column)
+		}
+	}
+}
+
+func TestFormatTokens(t *testing.T) {
+	paths, _ := filepath.Glob("../../projects/10/*/*.jack")
+	assert.NotEmpty(t, paths)
+
+	for _, jackPath := range paths {
+		t.Run(jackPath, func(t *testing.T) {
+			file, err := os.Open(jackPath)
+			assert.Nil(t, err)
+			defer file.Close()
+
+			tokens, err := TokenizeFile(jackPath, file)
+			assert.Nil(t, err)
+
+			var out strings.Builder
+			assert.Nil(t, FormatTokens(&out, tokens))
+
+			cmp, err := os.ReadFile(strings.TrimSuffix(jackPath, ".jack") + "T.xml")
+			assert.Nil(t, err)
+			assert.Equal(t, strings.ReplaceAll(string(cmp), "\r", ""), out.String())
+		})
+	}
+}
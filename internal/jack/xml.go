@@ -0,0 +1,271 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jack
+
+import (
+	"io"
+	"strconv"
+	"strings"
+)
+
+var xmlEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+
+// FormatTokens writes tokens in the XML format of the course's *T.xml files.
+func FormatTokens(w io.Writer, tokens []Token) (err error) {
+	if _, err = io.WriteString(w, "<tokens>\n"); err != nil {
+		return
+	}
+	for _, tok := range tokens {
+		if err = tok.FormatXML(w); err != nil {
+			return
+		}
+	}
+	_, err = io.WriteString(w, "</tokens>\n")
+	return
+}
+
+// FormatXML writes the token as a single XML element.
+func (tok Token) FormatXML(w io.Writer) (err error) {
+	kind := tok.Kind.String()
+	_, err = io.WriteString(w, "<"+kind+"> "+xmlEscaper.Replace(tok.Value)+" </"+kind+">\n")
+	return
+}
+
+type xmlWriter struct {
+	w     io.Writer
+	depth int
+	err   error
+}
+
+func (x *xmlWriter) line(str string) {
+	if x.err == nil {
+		_, x.err = io.WriteString(x.w, strings.Repeat("  ", x.depth)+str+"\n")
+	}
+}
+
+func (x *xmlWriter) open(tag string) {
+	x.line("<" + tag + ">")
+	x.depth += 1
+}
+
+func (x *xmlWriter) close(tag string) {
+	x.depth -= 1
+	x.line("</" + tag + ">")
+}
+
+func (x *xmlWriter) token(kind TokenKind, value string) {
+	x.line("<" + kind.String() + "> " + xmlEscaper.Replace(value) + " </" + kind.String() + ">")
+}
+
+func (x *xmlWriter) keyword(value string) {
+	x.token(TokenKeyword, value)
+}
+
+func (x *xmlWriter) symbol(value string) {
+	x.token(TokenSymbol, value)
+}
+
+func (x *xmlWriter) identifier(value string) {
+	x.token(TokenIdentifier, value)
+}
+
+// typ writes a type, which is a keyword for the builtin types.
+func (x *xmlWriter) typ(typ string) {
+	if Keywords[typ] {
+		x.keyword(typ)
+	} else {
+		x.identifier(typ)
+	}
+}
+
+func (x *xmlWriter) names(names []string) {
+	for idx, name := range names {
+		if idx > 0 {
+			x.symbol(",")
+		}
+		x.identifier(name)
+	}
+	x.symbol(";")
+}
+
+// FormatXML writes the parse tree of the class in the XML format of the
+// course's .xml files.
+func (class *Class) FormatXML(w io.Writer) (err error) {
+	x := &xmlWriter{w: w}
+
+	x.open("class")
+	x.keyword(KeywordClass)
+	x.identifier(class.Name)
+	x.symbol("{")
+	for _, dec := range class.Vars {
+		x.open("classVarDec")
+		x.keyword(dec.Kind)
+		x.typ(dec.Type)
+		x.names(dec.Names)
+		x.close("classVarDec")
+	}
+	for _, dec := range class.Subroutines {
+		x.subroutineDec(dec)
+	}
+	x.symbol("}")
+	x.close("class")
+
+	return x.err
+}
+
+func (x *xmlWriter) subroutineDec(dec SubroutineDec) {
+	x.open("subroutineDec")
+	x.keyword(dec.Kind)
+	x.typ(dec.ReturnType)
+	x.identifier(dec.Name)
+	x.symbol("(")
+	x.open("parameterList")
+	for idx, param := range dec.Params {
+		if idx > 0 {
+			x.symbol(",")
+		}
+		x.typ(param.Type)
+		x.identifier(param.Name)
+	}
+	x.close("parameterList")
+	x.symbol(")")
+
+	x.open("subroutineBody")
+	x.symbol("{")
+	for _, vars := range dec.Vars {
+		x.open("varDec")
+		x.keyword(KeywordVar)
+		x.typ(vars.Type)
+		x.names(vars.Names)
+		x.close("varDec")
+	}
+	x.statements(dec.Statements)
+	x.symbol("}")
+	x.close("subroutineBody")
+	x.close("subroutineDec")
+}
+
+func (x *xmlWriter) statements(stmts []Statement) {
+	x.open("statements")
+	for _, stmt := range stmts {
+		x.statement(stmt)
+	}
+	x.close("statements")
+}
+
+func (x *xmlWriter) block(stmts []Statement) {
+	x.symbol("{")
+	x.statements(stmts)
+	x.symbol("}")
+}
+
+func (x *xmlWriter) statement(stmt Statement) {
+	switch stmt := stmt.(type) {
+	case *LetStatement:
+		x.open("letStatement")
+		x.keyword(KeywordLet)
+		x.identifier(stmt.Name)
+		if stmt.Index != nil {
+			x.symbol("[")
+			x.expression(*stmt.Index)
+			x.symbol("]")
+		}
+		x.symbol("=")
+		x.expression(stmt.Value)
+		x.symbol(";")
+		x.close("letStatement")
+	case *IfStatement:
+		x.open("ifStatement")
+		x.keyword(KeywordIf)
+		x.symbol("(")
+		x.expression(stmt.Cond)
+		x.symbol(")")
+		x.block(stmt.Then)
+		if stmt.Else != nil {
+			x.keyword(KeywordElse)
+			x.block(stmt.Else)
+		}
+		x.close("ifStatement")
+	case *WhileStatement:
+		x.open("whileStatement")
+		x.keyword(KeywordWhile)
+		x.symbol("(")
+		x.expression(stmt.Cond)
+		x.symbol(")")
+		x.block(stmt.Body)
+		x.close("whileStatement")
+	case *DoStatement:
+		x.open("doStatement")
+		x.keyword(KeywordDo)
+		x.subroutineCall(stmt.Call)
+		x.symbol(";")
+		x.close("doStatement")
+	case *ReturnStatement:
+		x.open("returnStatement")
+		x.keyword(KeywordReturn)
+		if stmt.Value != nil {
+			x.expression(*stmt.Value)
+		}
+		x.symbol(";")
+		x.close("returnStatement")
+	}
+}
+
+func (x *xmlWriter) expression(expr Expression) {
+	x.open("expression")
+	x.term(expr.Term)
+	for _, op := range expr.Ops {
+		x.symbol(op.Op)
+		x.term(op.Term)
+	}
+	x.close("expression")
+}
+
+func (x *xmlWriter) term(term Term) {
+	x.open("term")
+	switch term := term.(type) {
+	case *IntegerConstant:
+		x.token(TokenIntegerConstant, strconv.Itoa(term.Value))
+	case *StringConstant:
+		x.token(TokenStringConstant, term.Value)
+	case *KeywordConstant:
+		x.keyword(term.Value)
+	case *VarTerm:
+		x.identifier(term.Name)
+	case *IndexTerm:
+		x.identifier(term.Name)
+		x.symbol("[")
+		x.expression(term.Index)
+		x.symbol("]")
+	case *SubroutineCall:
+		x.subroutineCall(*term)
+	case *ParenTerm:
+		x.symbol("(")
+		x.expression(term.Expr)
+		x.symbol(")")
+	case *UnaryTerm:
+		x.symbol(term.Op)
+		x.term(term.Term)
+	}
+	x.close("term")
+}
+
+func (x *xmlWriter) subroutineCall(call SubroutineCall) {
+	if call.Receiver != "" {
+		x.identifier(call.Receiver)
+		x.symbol(".")
+	}
+	x.identifier(call.Name)
+	x.symbol("(")
+	x.open("expressionList")
+	for idx, arg := range call.Args {
+		if idx > 0 {
+			x.symbol(",")
+		}
+		x.expression(arg)
+	}
+	x.close("expressionList")
+	x.symbol(")")
+}
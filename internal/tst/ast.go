@@ -0,0 +1,64 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tst
+
+type (
+	Script struct {
+		File     string
+		Commands []Command
+	}
+
+	// Command is a single script command. Repeat and while commands carry
+	// the commands of their block in Body.
+	Command struct {
+		Name string
+		Args []string
+		Line int
+
+		// Count is the number of iterations of a repeat command, or -1 to
+		// repeat forever.
+		Count int
+		Cond  Condition
+		Body  []Command
+	}
+
+	Condition struct {
+		Variable string
+		Op       string
+		Value    string
+	}
+
+	// OutputColumn is an entry of an output-list command, such as
+	// RAM[0]%D2.6.2.
+	OutputColumn struct {
+		Variable string
+		Format   byte
+		PadLeft  int
+		Width    int
+		PadRight int
+	}
+
+	// Simulator executes the program or chip loaded by a script.
+	Simulator interface {
+		// Load loads the file, or the directory, at filePath.
+		Load(filePath string) error
+
+		// Get returns the value of variable, such as RAM[0] or PC.
+		Get(variable string) (int, error)
+
+		// Set sets variable to value.
+		Set(variable string, value int) error
+
+		// Exec executes a simulator specific command such as ticktock or
+		// vmstep.
+		Exec(name string, args []string) error
+	}
+
+	// Widther is implemented by simulators whose variables are not all 16
+	// bits wide. The width is used for the default output format.
+	Widther interface {
+		Width(variable string) int
+	}
+)
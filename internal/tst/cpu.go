@@ -0,0 +1,116 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tst
+
+import (
+	"hack/internal/asm"
+	"hack/internal/cpu"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type (
+	// CPUSimulator runs .asm and .hack programs on the Hack CPU emulator.
+	CPUSimulator struct {
+		CPU *cpu.CPU
+	}
+)
+
+func NewCPUSimulator() *CPUSimulator {
+	return &CPUSimulator{CPU: cpu.New()}
+}
+
+func (sim *CPUSimulator) Load(filePath string) (err error) {
+	var file *os.File
+	if file, err = os.Open(filePath); err != nil {
+		return
+	}
+	defer file.Close()
+
+	if filepath.Ext(filePath) == ".hack" {
+		return sim.CPU.LoadHack(file)
+	}
+
+	var prog asm.Program
+	if prog, err = asm.ParseFile(filePath, file, 0); err != nil {
+		return
+	}
+	return sim.CPU.Load(prog)
+}
+
+func (sim *CPUSimulator) Get(variable string) (value int, err error) {
+	switch variable {
+	case "A":
+		return int(sim.CPU.A), nil
+	case "D":
+		return int(sim.CPU.D), nil
+	case "PC":
+		return int(sim.CPU.PC), nil
+	case "time":
+		return sim.CPU.Cycles, nil
+	}
+
+	name, index, ok := ParseIndexedVariable(variable)
+	switch {
+	case ok && name == "RAM" && index < cpu.RAMSize:
+		return int(sim.CPU.RAM[index]), nil
+	case ok && name == "ROM" && index < cpu.ROMSize:
+		return int(int16(sim.CPU.ROM[index])), nil
+	}
+	return 0, ErrVariableInvalid{variable: variable}
+}
+
+func (sim *CPUSimulator) Set(variable string, value int) (err error) {
+	switch variable {
+	case "A":
+		sim.CPU.A = int16(value)
+		return
+	case "D":
+		sim.CPU.D = int16(value)
+		return
+	case "PC":
+		sim.CPU.PC = uint16(value)
+		return
+	}
+
+	name, index, ok := ParseIndexedVariable(variable)
+	switch {
+	case ok && name == "RAM" && index < cpu.RAMSize:
+		sim.CPU.RAM[index] = int16(value)
+	case ok && name == "ROM" && index < cpu.ROMSize:
+		sim.CPU.ROM[index] = uint16(value)
+	default:
+		err = ErrVariableInvalid{variable: variable}
+	}
+	return
+}
+
+func (sim *CPUSimulator) Exec(name string, args []string) (err error) {
+	switch name {
+	case "ticktock", "tock":
+		sim.CPU.Step()
+	case "tick":
+	default:
+		err = ErrCommandInvalid{cmd: name}
+	}
+	return
+}
+
+// ParseIndexedVariable splits a variable such as RAM[16] into its name and
+// index.
+func ParseIndexedVariable(variable string) (name string, index int, ok bool) {
+	name, rest, found := strings.Cut(variable, "[")
+	if !found || !strings.HasSuffix(rest, "]") {
+		return variable, 0, false
+	}
+
+	var err error
+	if index, err = strconv.Atoi(strings.TrimSuffix(rest, "]")); err != nil || index < 0 {
+		return variable, 0, false
+	}
+	return name, index, true
+}
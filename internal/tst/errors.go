@@ -0,0 +1,83 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tst
+
+import (
+	"errors"
+	"strconv"
+)
+
+var (
+	ErrUnexpectedEOF = errors.New("unexpected end of script")
+	ErrNotLoaded     = errors.New("no program loaded")
+)
+
+type (
+	ErrUnexpectedToken struct {
+		token string
+	}
+
+	ErrValueInvalid struct {
+		value string
+	}
+
+	ErrFormatInvalid struct {
+		format string
+	}
+
+	ErrVariableInvalid struct {
+		variable string
+	}
+
+	ErrCommandInvalid struct {
+		cmd string
+	}
+
+	ErrSimulatorUnknown struct {
+		file string
+	}
+
+	ErrVMFilesMissing struct {
+		dir string
+	}
+
+	ErrComparison struct {
+		line     int
+		expected string
+		actual   string
+	}
+)
+
+func (err ErrUnexpectedToken) Error() string {
+	return "unexpected token: " + err.token
+}
+
+func (err ErrValueInvalid) Error() string {
+	return "invalid value: " + err.value
+}
+
+func (err ErrFormatInvalid) Error() string {
+	return "invalid format: " + err.format
+}
+
+func (err ErrVariableInvalid) Error() string {
+	return "invalid variable: " + err.variable
+}
+
+func (err ErrCommandInvalid) Error() string {
+	return "invalid command: " + err.cmd
+}
+
+func (err ErrSimulatorUnknown) Error() string {
+	return "no simulator for: " + err.file
+}
+
+func (err ErrVMFilesMissing) Error() string {
+	return "no .vm files in: " + err.dir
+}
+
+func (err ErrComparison) Error() string {
+	return "comparison failure at line " + strconv.Itoa(err.line) + ": expected " + err.expected + ", got " + err.actual
+}
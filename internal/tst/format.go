@@ -0,0 +1,54 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tst
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Header returns the column title, centered in the column.
+func (col OutputColumn) Header() string {
+	size := col.PadLeft + col.Width + col.PadRight
+	name := col.Variable
+	if len(name) > size {
+		name = name[:size]
+	}
+	left := (size - len(name)) / 2
+	return strings.Repeat(" ", left) + name + strings.Repeat(" ", size-left-len(name))
+}
+
+// FormatInt returns value formatted as the column specifies.
+func (col OutputColumn) FormatInt(value int) string {
+	var str string
+	switch col.Format {
+	case 'D':
+		str = strconv.Itoa(value)
+		str = strings.Repeat(" ", max(col.Width-len(str), 0)) + str
+	case 'B':
+		str = lastDigits(strconv.FormatUint(uint64(uint16(value)), 2), col.Width)
+	case 'X':
+		str = lastDigits(strings.ToUpper(strconv.FormatUint(uint64(uint16(value)), 16)), col.Width)
+	default:
+		return col.FormatString(strconv.Itoa(value))
+	}
+	return strings.Repeat(" ", col.PadLeft) + str + strings.Repeat(" ", col.PadRight)
+}
+
+// FormatString returns str left aligned in the column.
+func (col OutputColumn) FormatString(str string) string {
+	if len(str) > col.Width {
+		str = str[:col.Width]
+	}
+	return strings.Repeat(" ", col.PadLeft) + str + strings.Repeat(" ", col.Width-len(str)+col.PadRight)
+}
+
+// lastDigits returns the width rightmost digits of str, zero padded.
+func lastDigits(str string, width int) string {
+	if len(str) >= width {
+		return str[len(str)-width:]
+	}
+	return strings.Repeat("0", width-len(str)) + str
+}
@@ -0,0 +1,26 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tst
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeader(t *testing.T) {
+	assert.Equal(t, "  RAM[0]  ", OutputColumn{Variable: "RAM[0]", PadLeft: 2, Width: 6, PadRight: 2}.Header())
+	assert.Equal(t, "RAM[3006", OutputColumn{Variable: "RAM[3006]", PadLeft: 1, Width: 6, PadRight: 1}.Header())
+	assert.Equal(t, "in ", OutputColumn{Variable: "in", PadLeft: 1, Width: 1, PadRight: 1}.Header())
+	assert.Equal(t, "        x         ", OutputColumn{Variable: "x", PadLeft: 1, Width: 16, PadRight: 1}.Header())
+}
+
+func TestFormat(t *testing.T) {
+	assert.Equal(t, "     -91  ", OutputColumn{Format: 'D', PadLeft: 2, Width: 6, PadRight: 2}.FormatInt(-91))
+	assert.Equal(t, " 1111111111111111 ", OutputColumn{Format: 'B', PadLeft: 1, Width: 16, PadRight: 1}.FormatInt(-1))
+	assert.Equal(t, "  1  ", OutputColumn{Format: 'B', PadLeft: 2, Width: 1, PadRight: 2}.FormatInt(1))
+	assert.Equal(t, "00FF", OutputColumn{Format: 'X', Width: 4}.FormatInt(255))
+	assert.Equal(t, " 0+   ", OutputColumn{Format: 'S', PadLeft: 1, Width: 4, PadRight: 1}.FormatString("0+"))
+}
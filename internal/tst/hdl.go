@@ -0,0 +1,136 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tst
+
+import (
+	"hack/internal/cpu"
+	"hack/internal/hdl"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type (
+	// HDLSimulator runs .hdl chips on the gate-level simulator.
+	HDLSimulator struct {
+		Sim *hdl.Simulator
+
+		// Path lists the directories searched for the parts of a chip after
+		// the directory of the chip itself.
+		Path []string
+
+		// ForceHDL names the parts loaded from their .hdl file even though a
+		// builtin exists.
+		ForceHDL []string
+
+		dir string
+	}
+)
+
+func NewHDLSimulator() *HDLSimulator {
+	return &HDLSimulator{}
+}
+
+func (sim *HDLSimulator) Load(filePath string) (err error) {
+	sim.dir = filepath.Dir(filePath)
+	loader := hdl.NewLoader(append([]string{sim.dir}, sim.Path...)...)
+	for _, name := range sim.ForceHDL {
+		loader.ForceHDL[name] = true
+	}
+
+	var chip *hdl.Chip
+	if chip, err = loader.LoadFile(filePath); err != nil {
+		return
+	}
+	sim.Sim, err = hdl.New(chip, loader)
+	return
+}
+
+// memory returns the words of the builtin part a variable such as
+// RAM16K[3] or DRegister[] refers to, and the index of the word.
+func (sim *HDLSimulator) memory(variable string) (words []int16, index int, ok bool) {
+	name, index, ok := ParseIndexedVariable(variable)
+	if !ok {
+		if name, ok = strings.CutSuffix(variable, "[]"); !ok {
+			return
+		}
+	}
+	if words, ok = sim.Sim.Memory(name); !ok || index >= len(words) {
+		return nil, 0, false
+	}
+	return
+}
+
+func (sim *HDLSimulator) Get(variable string) (value int, err error) {
+	if words, index, ok := sim.memory(variable); ok {
+		return int(words[index]), nil
+	}
+	if value, err = sim.Sim.Get(variable); err != nil {
+		err = ErrVariableInvalid{variable: variable}
+	}
+	return
+}
+
+func (sim *HDLSimulator) Set(variable string, value int) (err error) {
+	if words, index, ok := sim.memory(variable); ok {
+		words[index] = int16(value)
+		sim.Sim.Eval()
+		return
+	}
+	return sim.Sim.Set(variable, value)
+}
+
+func (sim *HDLSimulator) Width(variable string) int {
+	if width := sim.Sim.Width(variable); width > 0 {
+		return width
+	}
+	return hdl.MaxWidth
+}
+
+func (sim *HDLSimulator) Exec(name string, args []string) (err error) {
+	switch name {
+	case "eval":
+		sim.Sim.Eval()
+	case "tick":
+		sim.Sim.Tick()
+	case "tock":
+		sim.Sim.Tock()
+	case "ticktock":
+		sim.Sim.Tick()
+		sim.Sim.Tock()
+	case "ROM32K":
+		if len(args) != 2 || args[0] != "load" {
+			return ErrCommandInvalid{cmd: name}
+		}
+		err = sim.loadROM(filepath.Join(sim.dir, args[1]))
+	default:
+		err = ErrCommandInvalid{cmd: name}
+	}
+	return
+}
+
+// loadROM loads a .hack program into the ROM32K part of the chip.
+func (sim *HDLSimulator) loadROM(filePath string) (err error) {
+	rom, ok := sim.Sim.Memory("ROM32K")
+	if !ok {
+		return ErrVariableInvalid{variable: "ROM32K"}
+	}
+
+	var file *os.File
+	if file, err = os.Open(filePath); err != nil {
+		return
+	}
+	defer file.Close()
+
+	c := cpu.New()
+	if err = c.LoadHack(file); err != nil {
+		return
+	}
+	for i, word := range c.ROM {
+		rom[i] = int16(word)
+	}
+	sim.Sim.Eval()
+	return
+}
@@ -0,0 +1,300 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tst
+
+import (
+	"hack/internal/diag"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type (
+	token struct {
+		text   string
+		quoted bool
+		line   int
+		column int
+	}
+
+	parser struct {
+		file   string
+		lines  []string
+		tokens []token
+		pos    int
+	}
+)
+
+func ParseString(str string) (script Script, err error) {
+	return Parse(strings.NewReader(str))
+}
+
+func Parse(r io.Reader) (script Script, err error) {
+	return ParseFile("", r)
+}
+
+func ParseFile(name string, r io.Reader) (script Script, err error) {
+	var src []byte
+	if src, err = io.ReadAll(r); err != nil {
+		return
+	}
+
+	p := &parser{
+		file:  name,
+		lines: strings.Split(string(src), "\n"),
+	}
+	if err = p.tokenize(string(src)); err != nil {
+		return
+	}
+
+	script.File = name
+	for p.pos < len(p.tokens) {
+		var cmd Command
+		if cmd, err = p.command(); err != nil {
+			return
+		}
+		script.Commands = append(script.Commands, cmd)
+	}
+
+	return
+}
+
+func (p *parser) tokenize(src string) error {
+	line, column := 1, 1
+	for i := 0; i < len(src); {
+		char := src[i]
+		switch {
+		case char == '\n':
+			line, column = line+1, 1
+			i += 1
+			continue
+		case char == ' ' || char == '\t' || char == '\r':
+		case strings.HasPrefix(src[i:], "//"):
+			for i < len(src) && src[i] != '\n' {
+				i += 1
+			}
+			continue
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return p.error(line, column, ErrUnexpectedEOF)
+			}
+			for _, c := range src[i : i+2+end+2] {
+				if c == '\n' {
+					line, column = line+1, 0
+				}
+				column += 1
+			}
+			i += 2 + end + 2
+			continue
+		case char == '"':
+			end := strings.IndexAny(src[i+1:], "\"\n")
+			if end < 0 || src[i+1+end] != '"' {
+				return p.error(line, column, ErrUnexpectedEOF)
+			}
+			p.tokens = append(p.tokens, token{text: src[i+1 : i+1+end], quoted: true, line: line, column: column})
+			column += end + 2
+			i += end + 2
+			continue
+		case strings.IndexByte(",;!{}", char) >= 0:
+			p.tokens = append(p.tokens, token{text: string(char), line: line, column: column})
+		case strings.IndexByte("<>=", char) >= 0:
+			end := i + 1
+			for end < len(src) && strings.IndexByte("<>=", src[end]) >= 0 {
+				end += 1
+			}
+			p.tokens = append(p.tokens, token{text: src[i:end], line: line, column: column})
+			column += end - i
+			i = end
+			continue
+		default:
+			end := i + 1
+			for end < len(src) && strings.IndexByte(" \t\r\n,;!{}\"<>=", src[end]) < 0 && !strings.HasPrefix(src[end:], "//") {
+				end += 1
+			}
+			p.tokens = append(p.tokens, token{text: src[i:end], line: line, column: column})
+			column += end - i
+			i = end
+			continue
+		}
+		column += 1
+		i += 1
+	}
+	return nil
+}
+
+func (p *parser) error(line, column int, err error) error {
+	var source string
+	if line-1 < len(p.lines) {
+		source = strings.TrimRight(p.lines[line-1], "\r")
+	}
+	return diag.Diagnostic{File: p.file, Line: line, Column: column, Source: source, Err: err}
+}
+
+func (p *parser) next() (tok token, err error) {
+	if p.pos >= len(p.tokens) {
+		line := len(p.lines)
+		err = p.error(line, 1, ErrUnexpectedEOF)
+		return
+	}
+	tok = p.tokens[p.pos]
+	p.pos += 1
+	return
+}
+
+func (p *parser) peek() (tok token, ok bool) {
+	if p.pos >= len(p.tokens) {
+		return
+	}
+	return p.tokens[p.pos], true
+}
+
+func (p *parser) expect(text string) (err error) {
+	var tok token
+	if tok, err = p.next(); err != nil {
+		return
+	}
+	if tok.text != text || tok.quoted {
+		err = p.error(tok.line, tok.column, ErrUnexpectedToken{token: tok.text})
+	}
+	return
+}
+
+func (p *parser) command() (cmd Command, err error) {
+	var tok token
+	if tok, err = p.next(); err != nil {
+		return
+	}
+	if tok.quoted || strings.IndexByte(",;!{}", tok.text[0]) >= 0 {
+		err = p.error(tok.line, tok.column, ErrUnexpectedToken{token: tok.text})
+		return
+	}
+
+	cmd.Name = tok.text
+	cmd.Line = tok.line
+
+	switch cmd.Name {
+	case "repeat":
+		cmd.Count = -1
+		if next, ok := p.peek(); ok && next.text != "{" {
+			p.pos += 1
+			if cmd.Count, err = strconv.Atoi(next.text); err != nil || cmd.Count < 0 {
+				err = p.error(next.line, next.column, ErrValueInvalid{value: next.text})
+				return
+			}
+		}
+		cmd.Body, err = p.block()
+		return
+	case "while":
+		var tokens [3]token
+		for i := range tokens {
+			if tokens[i], err = p.next(); err != nil {
+				return
+			}
+		}
+		cmd.Cond = Condition{Variable: tokens[0].text, Op: tokens[1].text, Value: tokens[2].text}
+		switch cmd.Cond.Op {
+		case "=", "<>", "<", ">", "<=", ">=":
+		default:
+			err = p.error(tokens[1].line, tokens[1].column, ErrUnexpectedToken{token: tokens[1].text})
+			return
+		}
+		cmd.Body, err = p.block()
+		return
+	}
+
+	for {
+		next, ok := p.peek()
+		if !ok {
+			return
+		}
+		if !next.quoted && (next.text == "," || next.text == ";" || next.text == "!") {
+			p.pos += 1
+			return
+		}
+		if !next.quoted && (next.text == "{" || next.text == "}") {
+			err = p.error(next.line, next.column, ErrUnexpectedToken{token: next.text})
+			return
+		}
+		cmd.Args = append(cmd.Args, next.text)
+		p.pos += 1
+	}
+}
+
+func (p *parser) block() (body []Command, err error) {
+	if err = p.expect("{"); err != nil {
+		return
+	}
+	for {
+		next, ok := p.peek()
+		if !ok {
+			_, err = p.next()
+			return
+		}
+		if next.text == "}" && !next.quoted {
+			p.pos += 1
+			return
+		}
+		var cmd Command
+		if cmd, err = p.command(); err != nil {
+			return
+		}
+		body = append(body, cmd)
+	}
+}
+
+// ParseOutputColumn parses an output-list entry. Entries without an explicit
+// format use the binary format over width bits.
+func ParseOutputColumn(str string, width int) (col OutputColumn, err error) {
+	variable, format, found := strings.Cut(str, "%")
+	col = OutputColumn{Variable: variable, Format: 'B', PadLeft: 1, Width: width, PadRight: 1}
+	if !found {
+		return
+	}
+
+	parts := strings.Split(format[min(1, len(format)):], ".")
+	if len(format) < 1 || strings.IndexByte("BDXS", format[0]) < 0 || len(parts) != 3 {
+		err = ErrFormatInvalid{format: str}
+		return
+	}
+	col.Format = format[0]
+
+	for i, ptr := range []*int{&col.PadLeft, &col.Width, &col.PadRight} {
+		if *ptr, err = strconv.Atoi(parts[i]); err != nil || *ptr < 0 {
+			err = ErrFormatInvalid{format: str}
+			return
+		}
+	}
+
+	return
+}
+
+// ParseValue parses a script value, either decimal or prefixed with %B, %X
+// or %D.
+func ParseValue(str string) (value int, err error) {
+	base := 10
+	digits := str
+	if len(str) > 2 && str[0] == '%' {
+		switch str[1] {
+		case 'B':
+			base = 2
+		case 'X':
+			base = 16
+		case 'D':
+		default:
+			err = ErrValueInvalid{value: str}
+			return
+		}
+		digits = str[2:]
+	}
+
+	var parsed int64
+	if parsed, err = strconv.ParseInt(digits, base, 32); err != nil {
+		err = ErrValueInvalid{value: str}
+		return
+	}
+	value = int(parsed)
+	return
+}
@@ -0,0 +1,81 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tst
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	script, err := ParseString(`
+// comment
+load Mult.asm,
+output-list RAM[0]%D2.6.2 /* block
+comment */ RAM[1]%D2.6.2;
+set RAM[0] -1;
+repeat 20 {
+  ticktock;
+}
+while out <> 75 {
+  tick, tock,
+}
+echo "hello, world";
+`)
+	assert.Nil(t, err)
+	assert.Equal(t, []Command{
+		{Name: "load", Args: []string{"Mult.asm"}, Line: 3},
+		{Name: "output-list", Args: []string{"RAM[0]%D2.6.2", "RAM[1]%D2.6.2"}, Line: 4},
+		{Name: "set", Args: []string{"RAM[0]", "-1"}, Line: 6},
+		{Name: "repeat", Line: 7, Count: 20, Body: []Command{
+			{Name: "ticktock", Line: 8},
+		}},
+		{Name: "while", Line: 10, Cond: Condition{Variable: "out", Op: "<>", Value: "75"}, Body: []Command{
+			{Name: "tick", Line: 11},
+			{Name: "tock", Line: 11},
+		}},
+		{Name: "echo", Args: []string{"hello, world"}, Line: 13},
+	}, script.Commands)
+}
+
+func TestParseError(t *testing.T) {
+	_, err := ParseString("repeat 3 {\n  ticktock;\n")
+	assert.Equal(t, "3:1: unexpected end of script", err.Error())
+
+	_, err = ParseString("set a 1, }")
+	assert.Equal(t, "1:10: unexpected token: }", err.Error())
+}
+
+func TestParseOutputColumn(t *testing.T) {
+	col, err := ParseOutputColumn("RAM[0]%D2.6.2", 16)
+	assert.Nil(t, err)
+	assert.Equal(t, OutputColumn{Variable: "RAM[0]", Format: 'D', PadLeft: 2, Width: 6, PadRight: 2}, col)
+
+	col, err = ParseOutputColumn("in", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, OutputColumn{Variable: "in", Format: 'B', PadLeft: 1, Width: 1, PadRight: 1}, col)
+
+	_, err = ParseOutputColumn("in%Q1.2.3", 1)
+	assert.Equal(t, ErrFormatInvalid{format: "in%Q1.2.3"}, err)
+}
+
+func TestParseValue(t *testing.T) {
+	for str, expected := range map[string]int{
+		"42":                 42,
+		"-1":                 -1,
+		"%D-5":               -5,
+		"%X1F":               31,
+		"%B0101":             5,
+		"%B1111111111111111": 65535,
+	} {
+		value, err := ParseValue(str)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, value, str)
+	}
+
+	_, err := ParseValue("%B012")
+	assert.Equal(t, ErrValueInvalid{value: "%B012"}, err)
+}
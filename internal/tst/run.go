@@ -0,0 +1,353 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tst
+
+import (
+	"bufio"
+	"errors"
+	"hack/internal/diag"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type (
+	// Runner executes test scripts. Files named by the script are read from
+	// Dir, while output files are written to OutDir.
+	Runner struct {
+		Dir    string
+		OutDir string
+
+		// Echo receives the messages of echo commands.
+		Echo io.Writer
+
+		Simulator Simulator
+
+		// HDLPath and ForceHDL set the fields of the same names of the HDL
+		// simulators loaded by the script.
+		HDLPath  []string
+		ForceHDL []string
+
+		script  Script
+		columns []OutputColumn
+		out     *os.File
+		outPath string
+		cmp     []string
+		outLine int
+		time    int
+		tick    bool
+	}
+)
+
+// Simulators maps the extension of a loaded file to the simulator able to run
+// it. The empty extension denotes a directory.
+var Simulators = map[string]func() Simulator{
+	".asm":  func() Simulator { return NewCPUSimulator() },
+	".hack": func() Simulator { return NewCPUSimulator() },
+	".hdl":  func() Simulator { return NewHDLSimulator() },
+	".vm":   func() Simulator { return NewVMSimulator() },
+	"":      func() Simulator { return NewVMSimulator() },
+}
+
+// RunFile parses and runs the script at filePath, reading and writing files
+// next to it.
+func RunFile(filePath string) (outPath string, err error) {
+	return (&Runner{Echo: os.Stdout}).RunFile(filePath)
+}
+
+// RunFile parses and runs the script at filePath, setting Dir and OutDir to
+// its directory.
+func (r *Runner) RunFile(filePath string) (outPath string, err error) {
+	var file *os.File
+	if file, err = os.Open(filePath); err != nil {
+		return
+	}
+	defer file.Close()
+
+	var script Script
+	if script, err = ParseFile(filePath, file); err != nil {
+		return
+	}
+
+	r.Dir = filepath.Dir(filePath)
+	r.OutDir = r.Dir
+	err = r.Run(script)
+	return r.outPath, err
+}
+
+func (r *Runner) Run(script Script) (err error) {
+	r.script = script
+	defer func() {
+		if r.out != nil {
+			if closeErr := r.out.Close(); err == nil {
+				err = closeErr
+			}
+			r.out = nil
+		}
+	}()
+
+	if err = r.exec(script.Commands); err != nil {
+		return
+	}
+	if r.cmp != nil && r.outLine < len(r.cmp) {
+		err = ErrComparison{line: r.outLine + 1, expected: r.cmp[r.outLine], actual: "end of output"}
+	}
+	return
+}
+
+func (r *Runner) exec(cmds []Command) (err error) {
+	for _, cmd := range cmds {
+		if err = r.execCommand(cmd); err != nil {
+			var d diag.Diagnostic
+			if !errors.As(err, &d) {
+				err = diag.Diagnostic{File: r.script.File, Line: cmd.Line, Column: 1, Err: err}
+			}
+			return
+		}
+	}
+	return
+}
+
+func (r *Runner) execCommand(cmd Command) (err error) {
+	switch cmd.Name {
+	case "repeat":
+		for i := 0; cmd.Count < 0 || i < cmd.Count; i++ {
+			if err = r.exec(cmd.Body); err != nil {
+				return
+			}
+		}
+	case "while":
+		var ok bool
+		for {
+			if ok, err = r.eval(cmd.Cond); err != nil || !ok {
+				return
+			}
+			if err = r.exec(cmd.Body); err != nil {
+				return
+			}
+		}
+	case "load":
+		return r.load(cmd.Args)
+	case "output-file":
+		return r.outputFile(cmd.Args)
+	case "compare-to":
+		return r.compareTo(cmd.Args)
+	case "output-list":
+		return r.outputList(cmd.Args)
+	case "output":
+		return r.output()
+	case "set":
+		if len(cmd.Args) != 2 {
+			return ErrCommandInvalid{cmd: cmd.Name}
+		}
+		var value int
+		if value, err = ParseValue(cmd.Args[1]); err != nil {
+			return
+		}
+		if r.Simulator == nil {
+			return ErrNotLoaded
+		}
+		return r.Simulator.Set(cmd.Args[0], value)
+	case "echo":
+		if r.Echo != nil {
+			_, err = io.WriteString(r.Echo, strings.Join(cmd.Args, " ")+"\n")
+		}
+	case "clear-echo":
+	default:
+		if r.Simulator == nil {
+			return ErrNotLoaded
+		}
+		switch cmd.Name {
+		case "tick":
+			r.tick = true
+		case "tock":
+			r.tick = false
+			r.time += 1
+		case "ticktock":
+			r.time += 1
+		}
+		return r.Simulator.Exec(cmd.Name, cmd.Args)
+	}
+	return
+}
+
+func (r *Runner) load(args []string) (err error) {
+	if len(args) > 1 {
+		return ErrCommandInvalid{cmd: "load"}
+	}
+
+	filePath := r.Dir
+	var ext string
+	if len(args) == 1 {
+		filePath = filepath.Join(r.Dir, args[0])
+		ext = filepath.Ext(args[0])
+	}
+
+	newSimulator, ok := Simulators[ext]
+	if !ok {
+		return ErrSimulatorUnknown{file: filePath}
+	}
+	r.Simulator = newSimulator()
+	if sim, ok := r.Simulator.(*HDLSimulator); ok {
+		sim.Path, sim.ForceHDL = r.HDLPath, r.ForceHDL
+	}
+	r.time = 0
+	r.tick = false
+	return r.Simulator.Load(filePath)
+}
+
+func (r *Runner) outputFile(args []string) (err error) {
+	if len(args) != 1 {
+		return ErrCommandInvalid{cmd: "output-file"}
+	}
+	if r.out != nil {
+		if err = r.out.Close(); err != nil {
+			return
+		}
+	}
+
+	outDir := r.OutDir
+	if outDir == "" {
+		outDir = r.Dir
+	}
+	r.outPath = filepath.Join(outDir, args[0])
+	r.out, err = os.Create(r.outPath)
+	return
+}
+
+func (r *Runner) compareTo(args []string) (err error) {
+	if len(args) != 1 {
+		return ErrCommandInvalid{cmd: "compare-to"}
+	}
+
+	var file *os.File
+	if file, err = os.Open(filepath.Join(r.Dir, args[0])); err != nil {
+		return
+	}
+	defer file.Close()
+
+	r.cmp = []string{}
+	s := bufio.NewScanner(file)
+	for s.Scan() {
+		r.cmp = append(r.cmp, strings.TrimRight(s.Text(), "\r"))
+	}
+	r.outLine = 0
+	return s.Err()
+}
+
+func (r *Runner) outputList(args []string) (err error) {
+	r.columns = r.columns[:0]
+	for _, arg := range args {
+		width := 16
+		if widther, ok := r.Simulator.(Widther); ok {
+			variable, _, _ := strings.Cut(arg, "%")
+			width = widther.Width(variable)
+		}
+
+		var col OutputColumn
+		if col, err = ParseOutputColumn(arg, width); err != nil {
+			return
+		}
+		r.columns = append(r.columns, col)
+	}
+
+	builder := strings.Builder{}
+	builder.WriteByte('|')
+	for _, col := range r.columns {
+		builder.WriteString(col.Header())
+		builder.WriteByte('|')
+	}
+	return r.writeLine(builder.String())
+}
+
+func (r *Runner) output() (err error) {
+	builder := strings.Builder{}
+	builder.WriteByte('|')
+	for _, col := range r.columns {
+		if col.Variable == "time" {
+			time := strconv.Itoa(r.time)
+			if r.tick {
+				time += "+"
+			}
+			builder.WriteString(col.FormatString(time))
+		} else {
+			if r.Simulator == nil {
+				return ErrNotLoaded
+			}
+			var value int
+			if value, err = r.Simulator.Get(col.Variable); err != nil {
+				return
+			}
+			builder.WriteString(col.FormatInt(value))
+		}
+		builder.WriteByte('|')
+	}
+	return r.writeLine(builder.String())
+}
+
+func (r *Runner) writeLine(line string) (err error) {
+	if r.out != nil {
+		if _, err = io.WriteString(r.out, line+"\n"); err != nil {
+			return
+		}
+	}
+
+	if r.cmp != nil {
+		if r.outLine >= len(r.cmp) {
+			return ErrComparison{line: r.outLine + 1, expected: "end of file", actual: line}
+		}
+		if !matchLine(r.cmp[r.outLine], line) {
+			return ErrComparison{line: r.outLine + 1, expected: r.cmp[r.outLine], actual: line}
+		}
+		r.outLine += 1
+	}
+	return
+}
+
+func (r *Runner) eval(cond Condition) (ok bool, err error) {
+	if r.Simulator == nil {
+		return false, ErrNotLoaded
+	}
+
+	var left, right int
+	if left, err = r.Simulator.Get(cond.Variable); err != nil {
+		return
+	}
+	if right, err = ParseValue(cond.Value); err != nil {
+		return
+	}
+
+	switch cond.Op {
+	case "=":
+		ok = left == right
+	case "<>":
+		ok = left != right
+	case "<":
+		ok = left < right
+	case ">":
+		ok = left > right
+	case "<=":
+		ok = left <= right
+	case ">=":
+		ok = left >= right
+	}
+	return
+}
+
+// matchLine compares an output line against its expected line, where '*'
+// matches any character.
+func matchLine(expected, actual string) bool {
+	if len(expected) != len(actual) {
+		return false
+	}
+	for i := range expected {
+		if expected[i] != '*' && expected[i] != actual[i] {
+			return false
+		}
+	}
+	return true
+}
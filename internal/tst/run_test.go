@@ -0,0 +1,111 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tst
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func runProject(t *testing.T, tstFilePath string) (outDir string, err error) {
+	return runProjectHDL(t, tstFilePath, nil, nil)
+}
+
+func runProjectHDL(t *testing.T, tstFilePath string, hdlPath, forceHDL []string) (outDir string, err error) {
+	file, err := os.Open(tstFilePath)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	defer file.Close()
+
+	script, err := ParseFile(tstFilePath, file)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	outDir = t.TempDir()
+	r := &Runner{Dir: filepath.Dir(tstFilePath), OutDir: outDir, HDLPath: hdlPath, ForceHDL: forceHDL}
+	err = r.Run(script)
+	return
+}
+
+func TestRunMult(t *testing.T) {
+	outDir, err := runProject(t, "../../projects/04/mult/Mult.tst")
+	assert.Nil(t, err)
+
+	out, _ := os.ReadFile(filepath.Join(outDir, "Mult.out"))
+	cmp, _ := os.ReadFile("../../projects/04/mult/Mult.cmp")
+	assert.Equal(t, string(cmp), string(out))
+}
+
+func TestRunFillAutomatic(t *testing.T) {
+	_, err := runProject(t, "../../projects/04/fill/FillAutomatic.tst")
+	assert.Nil(t, err)
+}
+
+func TestRunComparisonFailure(t *testing.T) {
+	dir := t.TempDir()
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "Prog.asm"), []byte("@5\nD=A\n@R0\nM=D\n"), 0o644))
+	assert.Nil(t, os.WriteFile(filepath.Join(dir, "Prog.cmp"), []byte("|RAM[0] |\n|    *4 |\n"), 0o644))
+
+	script, err := ParseString(`
+load Prog.asm,
+compare-to Prog.cmp,
+output-list RAM[0]%D1.5.1;
+repeat 4 { ticktock; }
+output;
+`)
+	assert.Nil(t, err)
+
+	err = (&Runner{Dir: dir}).Run(script)
+	assert.Equal(t, "6:1: comparison failure at line 2: expected |    *4 |, got |     5 |", err.Error())
+}
+
+func TestRunVME(t *testing.T) {
+	for _, tstFilePath := range []string{
+		"../../projects/07/StackArithmetic/StackTest/StackTestVME.tst",
+		"../../projects/07/MemoryAccess/BasicTest/BasicTestVME.tst",
+		"../../projects/08/ProgramFlow/BasicLoop/BasicLoopVME.tst",
+		"../../projects/08/FunctionCalls/NestedCall/NestedCallVME.tst",
+		"../../projects/08/FunctionCalls/StaticsTest/StaticsTestVME.tst",
+	} {
+		t.Run(filepath.Base(tstFilePath), func(t *testing.T) {
+			_, err := runProject(t, tstFilePath)
+			assert.Nil(t, err)
+		})
+	}
+}
+
+func TestRunVMEEmptyDir(t *testing.T) {
+	dir := t.TempDir()
+	assert.Equal(t, ErrVMFilesMissing{dir: dir}, NewVMSimulator().Load(dir))
+}
+
+func TestRunHDL(t *testing.T) {
+	hdlPath := []string{"../../projects/01", "../../projects/02"}
+	for _, tstFilePath := range []string{
+		"../../projects/01/Mux.tst",
+		"../../projects/01/DMux8Way.tst",
+		"../../projects/02/ALU.tst",
+		"../../projects/03/a/Bit.tst",
+		"../../projects/03/a/PC.tst",
+		"../../projects/03/b/RAM16K.tst",
+		"../../projects/05/CPU.tst",
+		"../../projects/05/ComputerMax.tst",
+	} {
+		t.Run(filepath.Base(tstFilePath), func(t *testing.T) {
+			_, err := runProjectHDL(t, tstFilePath, hdlPath, nil)
+			assert.Nil(t, err)
+		})
+	}
+}
+
+func TestRunHDLForced(t *testing.T) {
+	_, err := runProjectHDL(t, "../../projects/03/a/Bit.tst", []string{"../../projects/01"}, []string{"Mux", "Not", "And", "Or"})
+	assert.Nil(t, err)
+}
@@ -0,0 +1,127 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tst
+
+import (
+	"hack/internal/vm"
+	"hack/internal/vme"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type (
+	// VMSimulator runs .vm files, or all the .vm files of a directory, on the
+	// VM emulator.
+	VMSimulator struct {
+		Machine *vme.Machine
+	}
+)
+
+func NewVMSimulator() *VMSimulator {
+	return &VMSimulator{Machine: vme.New()}
+}
+
+func (sim *VMSimulator) Load(filePath string) (err error) {
+	filePaths := []string{filePath}
+
+	var info os.FileInfo
+	if info, err = os.Stat(filePath); err != nil {
+		return
+	} else if info.IsDir() {
+		if filePaths, err = filepath.Glob(filepath.Join(filePath, "*.vm")); err != nil {
+			return
+		}
+		if len(filePaths) == 0 {
+			return ErrVMFilesMissing{dir: filePath}
+		}
+	}
+
+	for _, filePath := range filePaths {
+		var prog vm.Program
+		if prog, err = loadVM(filePath); err != nil {
+			return
+		}
+		sim.Machine.Load(strings.TrimSuffix(filepath.Base(filePath), ".vm"), prog)
+	}
+	return sim.Machine.Link()
+}
+
+func loadVM(filePath string) (prog vm.Program, err error) {
+	var file *os.File
+	if file, err = os.Open(filePath); err != nil {
+		return
+	}
+	defer file.Close()
+
+	return vm.ParseFile(filePath, file, 0)
+}
+
+// vmSegments maps the segment variables of the VM emulator to the RAM word
+// holding their base address.
+var vmSegments = map[string]int{
+	"sp":       vme.SP,
+	"local":    vme.LCL,
+	"argument": vme.ARG,
+	"this":     vme.THIS,
+	"that":     vme.THAT,
+}
+
+func (sim *VMSimulator) address(variable string) (addr int, ok bool) {
+	if addr, ok = vmSegments[variable]; ok {
+		return
+	}
+
+	name, index, ok := ParseIndexedVariable(variable)
+	if !ok {
+		return
+	}
+	switch name {
+	case "RAM":
+		addr = index
+	case "temp":
+		addr = vm.TempBase + index
+	case "pointer":
+		addr = vm.PointerBase + index
+	default:
+		var base int
+		if base, ok = vmSegments[name]; !ok || base == vme.SP {
+			return 0, false
+		}
+		addr = int(sim.Machine.RAM[base]) + index
+	}
+	return addr, addr >= 0 && addr < vme.RAMSize
+}
+
+func (sim *VMSimulator) Get(variable string) (value int, err error) {
+	if variable == "time" {
+		return sim.Machine.Steps, nil
+	}
+
+	addr, ok := sim.address(variable)
+	if !ok {
+		return 0, ErrVariableInvalid{variable: variable}
+	}
+	return int(sim.Machine.RAM[addr]), nil
+}
+
+func (sim *VMSimulator) Set(variable string, value int) (err error) {
+	addr, ok := sim.address(variable)
+	if !ok {
+		return ErrVariableInvalid{variable: variable}
+	}
+	sim.Machine.RAM[addr] = int16(value)
+	return
+}
+
+func (sim *VMSimulator) Exec(name string, args []string) (err error) {
+	switch name {
+	case "vmstep":
+		err = sim.Machine.Step()
+	default:
+		err = ErrCommandInvalid{cmd: name}
+	}
+	return
+}
@@ -4,7 +4,11 @@
 
 package vm
 
+import "strconv"
+
 type (
+	Mode uint
+
 	Command int
 
 	Segment int
@@ -13,5 +17,32 @@ type (
 		Command Command
 		Segment Segment
 		Index   int16
+		Label   string
+
+		Function string
+		Count    int16
+	}
+
+	// Source records the origin of each statement of a parsed program.
+	Source struct {
+		File  string
+		Lines []string
+
+		// StmtLines holds the 1-based source line of each statement.
+		StmtLines []int
 	}
 )
+
+func (cmd Command) String() string {
+	if str, ok := CommandToString[cmd]; ok {
+		return str
+	}
+	return "Command(" + strconv.Itoa(int(cmd)) + ")"
+}
+
+func (seg Segment) String() string {
+	if str, ok := SegmentToString[seg]; ok {
+		return str
+	}
+	return "Segment(" + strconv.Itoa(int(seg)) + ")"
+}
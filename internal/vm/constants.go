@@ -4,6 +4,15 @@
 
 package vm
 
+import (
+	"hack/internal/asm"
+	"regexp"
+)
+
+// AllErrors makes the parser report every diagnostic instead of stopping at
+// the first one.
+const AllErrors Mode = 1
+
 const (
 	CommandPush Command = iota
 	CommandPop
@@ -19,6 +28,14 @@ const (
 	CommandAnd
 	CommandOr
 	CommandNot
+
+	CommandLabel
+	CommandGoto
+	CommandIfGoto
+
+	CommandFunction
+	CommandCall
+	CommandReturn
 )
 
 const (
@@ -33,7 +50,28 @@ const (
 	SegmentTemp
 )
 
+const (
+	PointerBase = 3
+	PointerSize = 2
+
+	TempBase = 5
+	TempSize = 8
+
+	FrameSize = 5
+
+	StackBase = 256
+)
+
+const BootstrapFunction = "Sys.init"
+
+const (
+	FunctionMultiply = "Math.multiply"
+	FunctionDivide   = "Math.divide"
+)
+
 var (
+	SymbolRegex = regexp.MustCompile("^[a-zA-Z_.:][0-9a-zA-Z_.:]*$")
+
 	StringToCommand = map[string]Command{
 		"push": CommandPush,
 		"pop":  CommandPop,
@@ -49,6 +87,14 @@ var (
 		"and": CommandAnd,
 		"or":  CommandOr,
 		"not": CommandNot,
+
+		"label":   CommandLabel,
+		"goto":    CommandGoto,
+		"if-goto": CommandIfGoto,
+
+		"function": CommandFunction,
+		"call":     CommandCall,
+		"return":   CommandReturn,
 	}
 
 	StringToSegment = map[string]Segment{
@@ -61,4 +107,47 @@ var (
 		"pointer":  SegmentPointer,
 		"temp":     SegmentTemp,
 	}
+
+	CommandToString = map[Command]string{
+		CommandPush: "push",
+		CommandPop:  "pop",
+
+		CommandAdd: "add",
+		CommandSub: "sub",
+		CommandNeg: "neg",
+
+		CommandEq: "eq",
+		CommandGt: "gt",
+		CommandLt: "lt",
+
+		CommandAnd: "and",
+		CommandOr:  "or",
+		CommandNot: "not",
+
+		CommandLabel:  "label",
+		CommandGoto:   "goto",
+		CommandIfGoto: "if-goto",
+
+		CommandFunction: "function",
+		CommandCall:     "call",
+		CommandReturn:   "return",
+	}
+
+	SegmentToString = map[Segment]string{
+		SegmentArgument: "argument",
+		SegmentLocal:    "local",
+		SegmentStatic:   "static",
+		SegmentConstant: "constant",
+		SegmentThis:     "this",
+		SegmentThat:     "that",
+		SegmentPointer:  "pointer",
+		SegmentTemp:     "temp",
+	}
+
+	SegmentToSymbol = map[Segment]string{
+		SegmentArgument: asm.SymbolARG,
+		SegmentLocal:    asm.SymbolLCL,
+		SegmentThis:     asm.SymbolTHIS,
+		SegmentThat:     asm.SymbolTHAT,
+	}
 )
@@ -4,6 +4,8 @@
 
 package vm
 
+import "strconv"
+
 type (
 	ErrStatementInvalid struct {
 		stmt string
@@ -16,6 +18,36 @@ type (
 	ErrSegmentInvalid struct {
 		seg string
 	}
+
+	ErrIndexInvalid struct {
+		index string
+	}
+
+	ErrLabelInvalid struct {
+		label string
+	}
+
+	ErrFunctionInvalid struct {
+		function string
+	}
+
+	ErrFunctionUndefined struct {
+		function string
+	}
+
+	ErrUnhandledCommand struct {
+		cmd Command
+	}
+
+	ErrUnhandledSegment struct {
+		cmd Command
+		seg Segment
+	}
+
+	ErrIndexOutOfRange struct {
+		seg   Segment
+		index int16
+	}
 )
 
 func (err ErrStatementInvalid) Error() string {
@@ -29,3 +61,31 @@ func (err ErrCommandInvalid) Error() string {
 func (err ErrSegmentInvalid) Error() string {
 	return "invalid segment: " + err.seg
 }
+
+func (err ErrIndexInvalid) Error() string {
+	return "invalid index: " + err.index
+}
+
+func (err ErrLabelInvalid) Error() string {
+	return "invalid label: " + err.label
+}
+
+func (err ErrFunctionInvalid) Error() string {
+	return "invalid function: " + err.function
+}
+
+func (err ErrFunctionUndefined) Error() string {
+	return "undefined function: " + err.function
+}
+
+func (err ErrUnhandledCommand) Error() string {
+	return "unhandled command: " + err.cmd.String()
+}
+
+func (err ErrUnhandledSegment) Error() string {
+	return "unhandled segment for " + err.cmd.String() + ": " + err.seg.String()
+}
+
+func (err ErrIndexOutOfRange) Error() string {
+	return "index out of range for " + err.seg.String() + ": " + strconv.Itoa(int(err.index))
+}
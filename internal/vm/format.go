@@ -0,0 +1,50 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vm
+
+import (
+	"io"
+	"strconv"
+	"strings"
+)
+
+func FormatString(prog Program) (str string, err error) {
+	builder := strings.Builder{}
+	err = prog.Format(&builder)
+	str = builder.String()
+	return
+}
+
+// Format writes prog as VM source, one statement per line.
+func (prog Program) Format(w io.Writer) (err error) {
+	for idx, stmt := range prog {
+		if idx > 0 {
+			if _, err = w.Write([]byte{'\n'}); err != nil {
+				return
+			}
+		}
+		if err = stmt.Format(w); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (stmt Statement) Format(w io.Writer) (err error) {
+	_, err = w.Write([]byte(stmt.String()))
+	return
+}
+
+func (stmt Statement) String() string {
+	switch stmt.Command {
+	case CommandPush, CommandPop:
+		return stmt.Command.String() + " " + stmt.Segment.String() + " " + strconv.Itoa(int(stmt.Index))
+	case CommandLabel, CommandGoto, CommandIfGoto:
+		return stmt.Command.String() + " " + stmt.Label
+	case CommandFunction, CommandCall:
+		return stmt.Command.String() + " " + stmt.Function + " " + strconv.Itoa(int(stmt.Count))
+	}
+	return stmt.Command.String()
+}
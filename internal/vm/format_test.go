@@ -0,0 +1,33 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormat(t *testing.T) {
+	src := strings.Trim(`
+function Main.main 2
+push constant 7
+pop local 1
+label LOOP
+push local 1
+if-goto LOOP
+call Math.multiply 2
+not
+return
+`, "\n")
+
+	prog, err := ParseString(src)
+	assert.Nil(t, err)
+
+	str, err := FormatString(prog)
+	assert.Nil(t, err)
+	assert.Equal(t, src, str)
+}
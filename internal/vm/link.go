@@ -0,0 +1,45 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vm
+
+// Undefined returns the functions called by progs, or listed in roots, that
+// none of progs defines, in order of first reference.
+func Undefined(roots []string, progs ...Program) (functions []string) {
+	defined := map[string]bool{}
+	for _, prog := range progs {
+		for _, stmt := range prog {
+			if stmt.Command == CommandFunction {
+				defined[stmt.Function] = true
+			}
+		}
+	}
+
+	seen := map[string]bool{}
+	add := func(function string) {
+		if !defined[function] && !seen[function] {
+			seen[function] = true
+			functions = append(functions, function)
+		}
+	}
+	for _, function := range roots {
+		add(function)
+	}
+	for _, prog := range progs {
+		for _, stmt := range prog {
+			if stmt.Command == CommandCall {
+				add(stmt.Function)
+			}
+		}
+	}
+	return
+}
+
+// Link checks that progs define every function they call and every root.
+func Link(roots []string, progs ...Program) (err error) {
+	if undefined := Undefined(roots, progs...); len(undefined) > 0 {
+		err = ErrFunctionUndefined{function: undefined[0]}
+	}
+	return
+}
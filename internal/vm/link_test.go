@@ -0,0 +1,34 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLink(t *testing.T) {
+	main, err := ParseString(`
+function Main.main 0
+call Math.multiply 2
+call Main.f 0
+call Output.printInt 1
+call Math.multiply 2
+return
+function Main.f 0
+return
+`)
+	assert.Nil(t, err)
+	math, err := ParseString(`
+function Math.multiply 0
+return
+`)
+	assert.Nil(t, err)
+
+	assert.Equal(t, []string{"Sys.init", "Math.multiply", "Output.printInt"}, Undefined([]string{"Sys.init"}, main))
+	assert.Equal(t, []string{"Output.printInt"}, Undefined(nil, main, math))
+	assert.Equal(t, ErrFunctionUndefined{function: "Output.printInt"}, Link([]string{"Main.main"}, main, math))
+}
@@ -0,0 +1,307 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vm
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+type (
+	// Rule is an optimization of VM code. Apply returns a program equivalent
+	// to prog, and reports whether it differs from it.
+	//
+	// Rules assume that calls to Math.multiply and Math.divide are those of
+	// the Jack OS. They never remove the first reference to a static
+	// variable, so that statics keep the address the translator and the VM
+	// emulator allocate them in order of first appearance.
+	Rule struct {
+		Name  string
+		Apply func(prog Program) (optimized Program, changed bool)
+	}
+
+	// Stats counts the statements each rule removed.
+	Stats map[string]int
+)
+
+// Rules holds every rule, in the order Optimize should apply them.
+var Rules = []Rule{
+	{Name: "constant-folding", Apply: foldConstants},
+	{Name: "strength-reduction", Apply: reduceStrength},
+	{Name: "push-pop", Apply: removePushPops},
+	{Name: "dead-code", Apply: removeDeadCode},
+}
+
+// Optimize applies rules in turn until none of them changes the program, and
+// returns how many statements each one removed.
+func (prog Program) Optimize(rules ...Rule) (optimized Program, stats Stats) {
+	optimized, stats = prog, Stats{}
+	for changed := true; changed; {
+		changed = false
+		for _, rule := range rules {
+			size := len(optimized)
+			var ruleChanged bool
+			if optimized, ruleChanged = rule.Apply(optimized); ruleChanged {
+				stats[rule.Name] += size - len(optimized)
+				changed = true
+			}
+		}
+	}
+	return
+}
+
+// Removed returns the number of statements all rules removed.
+func (stats Stats) Removed() (total int) {
+	for _, count := range stats {
+		total += count
+	}
+	return
+}
+
+// String describes stats, listing rules in the order of Rules.
+func (stats Stats) String() string {
+	builder := strings.Builder{}
+	if removed := stats.Removed(); removed == 1 {
+		builder.WriteString("1 statement removed")
+	} else {
+		fmt.Fprintf(&builder, "%d statements removed", removed)
+	}
+
+	var counts []string
+	for _, rule := range Rules {
+		if count := stats[rule.Name]; count != 0 {
+			counts = append(counts, fmt.Sprintf("%s %d", rule.Name, count))
+		}
+	}
+	if len(counts) > 0 {
+		fmt.Fprintf(&builder, " (%s)", strings.Join(counts, ", "))
+	}
+	return builder.String()
+}
+
+// foldConstants evaluates arithmetic on constants, as in push constant 2
+// push constant 3 add, when the result takes fewer statements to push.
+func foldConstants(prog Program) (optimized Program, changed bool) {
+	for _, stmt := range prog {
+		optimized = append(optimized, stmt)
+
+		var value int16
+		var start int
+		switch stmt.Command {
+		case CommandNeg, CommandNot:
+			var ok bool
+			if value, start, ok = constantBefore(optimized, len(optimized)); !ok {
+				continue
+			}
+		case CommandAdd, CommandSub, CommandAnd, CommandOr, CommandEq, CommandGt, CommandLt, CommandCall:
+			if stmt.Command == CommandCall && !isCall(stmt, FunctionMultiply) {
+				continue
+			}
+			y, yStart, ok := constantBefore(optimized, len(optimized)-1)
+			if !ok {
+				continue
+			}
+			x, xStart, ok := constantBefore(optimized, yStart)
+			if !ok {
+				continue
+			}
+			value, start = evaluate(stmt.Command, x, y), xStart
+		default:
+			continue
+		}
+
+		if folded := pushConstant(value); len(folded) < len(optimized)-start {
+			optimized = append(optimized[:start], folded...)
+			changed = true
+		}
+	}
+	return
+}
+
+// reduceStrength replaces calls to Math.multiply by 0, 1, -1 or 2 and to
+// Math.divide by 1 with cheaper statements.
+func reduceStrength(prog Program) (optimized Program, changed bool) {
+	for _, stmt := range prog {
+		optimized = append(optimized, stmt)
+		end := len(optimized) - 1
+
+		switch {
+		case isCall(stmt, FunctionMultiply):
+			// x * k, where x only needs to be pushed again when k is 0 or 2.
+			if k, start, ok := constantBefore(optimized, end); ok {
+				switch {
+				case k == 1 || k == -1:
+					optimized = append(optimized[:start], multiply(k, nil)...)
+					changed = true
+				case (k == 0 || k == 2) && start > 0 && isPush(optimized[start-1], k):
+					x := optimized[start-1]
+					optimized = append(optimized[:start-1], multiply(k, &x)...)
+					changed = true
+				}
+				continue
+			}
+			// k * x, where x is pushed by a single statement.
+			if end > 0 && optimized[end-1].Command == CommandPush {
+				x := optimized[end-1]
+				if k, start, ok := constantBefore(optimized, end-1); ok && k >= -1 && k <= 2 && isPush(x, k) {
+					optimized = append(optimized[:start], multiply(k, &x)...)
+					changed = true
+				}
+			}
+		case isCall(stmt, FunctionDivide):
+			if k, start, ok := constantBefore(optimized, end); ok && k == 1 {
+				optimized = optimized[:start]
+				changed = true
+			}
+		}
+	}
+	return
+}
+
+// removePushPops removes pushes immediately popped back to where they come
+// from, as in push local 0 pop local 0.
+func removePushPops(prog Program) (optimized Program, changed bool) {
+	first := firstStatics(prog)
+	for idx := 0; idx < len(prog); idx++ {
+		if idx+1 < len(prog) && !first[idx] {
+			push, pop := prog[idx], prog[idx+1]
+			if push.Command == CommandPush && pop.Command == CommandPop && push.Segment == pop.Segment && push.Index == pop.Index {
+				idx, changed = idx+1, true
+				continue
+			}
+		}
+		optimized = append(optimized, prog[idx])
+	}
+	return
+}
+
+// removeDeadCode removes the statements between a goto or a return and the
+// next label or function.
+func removeDeadCode(prog Program) (optimized Program, changed bool) {
+	first := firstStatics(prog)
+	reachable := true
+	for idx, stmt := range prog {
+		switch {
+		case stmt.Command == CommandLabel || stmt.Command == CommandFunction:
+			reachable = true
+		case !reachable && !first[idx]:
+			changed = true
+			continue
+		case stmt.Command == CommandGoto || stmt.Command == CommandReturn:
+			reachable = false
+		}
+		optimized = append(optimized, stmt)
+	}
+	return
+}
+
+// firstStatics returns the indexes of the statements referring first to
+// each static variable.
+func firstStatics(prog Program) map[int]bool {
+	seen := map[int16]bool{}
+	first := map[int]bool{}
+	for idx, stmt := range prog {
+		if stmt.Segment == SegmentStatic && !seen[stmt.Index] {
+			seen[stmt.Index] = true
+			first[idx] = true
+		}
+	}
+	return first
+}
+
+// isPush reports whether stmt is a push multiply may use for k. Pushes of
+// static variables are never dropped, even when k is 0.
+func isPush(stmt Statement, k int16) bool {
+	return stmt.Command == CommandPush && (k != 0 || stmt.Segment != SegmentStatic)
+}
+
+// constantBefore evaluates the constant expression ending right before
+// prog[end], a push constant followed by neg and not statements, and returns
+// the index of its first statement.
+func constantBefore(prog Program, end int) (value int16, start int, ok bool) {
+	start = end - 1
+	for start >= 0 && (prog[start].Command == CommandNeg || prog[start].Command == CommandNot) {
+		start -= 1
+	}
+	if start < 0 || prog[start].Command != CommandPush || prog[start].Segment != SegmentConstant {
+		return 0, 0, false
+	}
+
+	value = prog[start].Index
+	for _, stmt := range prog[start+1 : end] {
+		if stmt.Command == CommandNeg {
+			value = -value
+		} else {
+			value = ^value
+		}
+	}
+	return value, start, true
+}
+
+// pushConstant returns the shortest statements pushing value.
+func pushConstant(value int16) Program {
+	switch {
+	case value >= 0:
+		return Program{{Command: CommandPush, Segment: SegmentConstant, Index: value}}
+	case value == math.MinInt16:
+		return Program{{Command: CommandPush, Segment: SegmentConstant, Index: math.MaxInt16}, {Command: CommandNot}}
+	default:
+		return Program{{Command: CommandPush, Segment: SegmentConstant, Index: -value}, {Command: CommandNeg}}
+	}
+}
+
+// multiply returns the statements multiplying by k the value x pushes, or
+// the top of the stack if x is nil.
+func multiply(k int16, x *Statement) Program {
+	var prog Program
+	if x != nil && k != 0 {
+		prog = append(prog, *x)
+	}
+	switch k {
+	case 0:
+		prog = append(prog, pushConstant(0)...)
+	case -1:
+		prog = append(prog, Statement{Command: CommandNeg})
+	case 2:
+		prog = append(prog, *x, Statement{Command: CommandAdd})
+	}
+	return prog
+}
+
+// evaluate returns the result of cmd on x and y, a call being one to
+// Math.multiply.
+func evaluate(cmd Command, x, y int16) int16 {
+	switch cmd {
+	case CommandAdd:
+		return x + y
+	case CommandSub:
+		return x - y
+	case CommandAnd:
+		return x & y
+	case CommandOr:
+		return x | y
+	case CommandEq:
+		return truth(x == y)
+	case CommandGt:
+		return truth(x > y)
+	case CommandLt:
+		return truth(x < y)
+	case CommandCall:
+		return x * y
+	}
+	return 0
+}
+
+func truth(b bool) int16 {
+	if b {
+		return -1
+	}
+	return 0
+}
+
+func isCall(stmt Statement, function string) bool {
+	return stmt.Command == CommandCall && stmt.Function == function && stmt.Count == 2
+}
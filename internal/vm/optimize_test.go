@@ -0,0 +1,93 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOptimizeRules(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		rule     func(Program) (Program, bool)
+		src, out string
+	}{
+		{"add", foldConstants, "push constant 2\npush constant 3\nadd", "push constant 5"},
+		{"nested", foldConstants, "push constant 1\npush constant 2\npush constant 3\nsub\nsub", "push constant 2"},
+		{"negative", foldConstants, "push constant 2\npush constant 3\nsub", "push constant 1\nneg"},
+		{"unary chain", foldConstants, "push constant 5\nneg\nneg", "push constant 5"},
+		{"minimum", foldConstants, "push constant 16384\npush constant 16384\nadd\nneg", "push constant 32767\nnot"},
+		{"comparison", foldConstants, "push constant 2\npush constant 3\nlt", "push constant 1\nneg"},
+		{"multiply", foldConstants, "push constant 6\npush constant 7\ncall Math.multiply 2", "push constant 42"},
+		{"true", foldConstants, "push constant 0\nnot", "push constant 0\nnot"},
+		{"negative constant", foldConstants, "push constant 1\nneg", "push constant 1\nneg"},
+		{"variable", foldConstants, "push local 0\npush constant 3\nadd", "push local 0\npush constant 3\nadd"},
+		{"label", foldConstants, "push constant 1\nlabel L\npush constant 2\nadd", "push constant 1\nlabel L\npush constant 2\nadd"},
+		{"times 1", reduceStrength, "push local 0\npush local 1\nadd\npush constant 1\ncall Math.multiply 2", "push local 0\npush local 1\nadd"},
+		{"times -1", reduceStrength, "push local 0\npush local 1\nadd\npush constant 1\nneg\ncall Math.multiply 2", "push local 0\npush local 1\nadd\nneg"},
+		{"times 0", reduceStrength, "push local 0\npush constant 0\ncall Math.multiply 2", "push constant 0"},
+		{"times 2", reduceStrength, "push local 0\npush constant 2\ncall Math.multiply 2", "push local 0\npush local 0\nadd"},
+		{"2 times", reduceStrength, "push constant 2\npush that 1\ncall Math.multiply 2", "push that 1\npush that 1\nadd"},
+		{"-1 times", reduceStrength, "push constant 1\nneg\npush that 1\ncall Math.multiply 2", "push that 1\nneg"},
+		{"expression times 2", reduceStrength, "push local 0\npush local 1\nadd\npush constant 2\ncall Math.multiply 2", "push local 0\npush local 1\nadd\npush constant 2\ncall Math.multiply 2"},
+		{"static times 0", reduceStrength, "push static 0\npush constant 0\ncall Math.multiply 2", "push static 0\npush constant 0\ncall Math.multiply 2"},
+		{"times 3", reduceStrength, "push local 0\npush constant 3\ncall Math.multiply 2", "push local 0\npush constant 3\ncall Math.multiply 2"},
+		{"divide by 1", reduceStrength, "push local 0\npush constant 1\ncall Math.divide 2", "push local 0"},
+		{"1 divided", reduceStrength, "push constant 1\npush local 0\ncall Math.divide 2", "push constant 1\npush local 0\ncall Math.divide 2"},
+		{"push pop", removePushPops, "push local 0\npop local 0\npush local 1\npop local 2", "push local 1\npop local 2"},
+		{"first static", removePushPops, "push static 0\npop static 0\npush static 0\npop static 0", "push static 0\npop static 0"},
+		{"goto", removeDeadCode, "goto L\npush local 0\nlabel L\npush local 1", "goto L\nlabel L\npush local 1"},
+		{"return", removeDeadCode, "function f 0\nreturn\npush local 0\nfunction g 0", "function f 0\nreturn\nfunction g 0"},
+		{"dead static", removeDeadCode, "goto L\npush static 1\npush static 1\nlabel L\npush static 1", "goto L\npush static 1\nlabel L\npush static 1"},
+		{"if-goto", removeDeadCode, "if-goto L\npush local 0\nlabel L", "if-goto L\npush local 0\nlabel L"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			prog, err := ParseString(tc.src)
+			assert.Nil(t, err)
+
+			optimized, changed := tc.rule(prog)
+			str, err := FormatString(optimized)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.out, str)
+			assert.Equal(t, tc.src != tc.out, changed)
+		})
+	}
+}
+
+func TestOptimize(t *testing.T) {
+	prog, err := ParseString(`function Main.main 1
+push constant 3
+push constant 4
+call Math.multiply 2
+push local 0
+push constant 2
+call Math.multiply 2
+add
+pop local 0
+push local 0
+pop local 0
+push constant 0
+return
+push constant 1`)
+	assert.Nil(t, err)
+
+	optimized, stats := prog.Optimize(Rules...)
+	str, err := FormatString(optimized)
+	assert.Nil(t, err)
+	assert.Equal(t, `function Main.main 1
+push constant 12
+push local 0
+push local 0
+add
+add
+pop local 0
+push constant 0
+return`, str)
+	assert.Equal(t, Stats{"constant-folding": 2, "strength-reduction": 0, "push-pop": 2, "dead-code": 1}, stats)
+	assert.Equal(t, "5 statements removed (constant-folding 2, push-pop 2, dead-code 1)", stats.String())
+	assert.Equal(t, "1 statement removed (dead-code 1)", Stats{"dead-code": 1}.String())
+}
@@ -6,34 +6,86 @@ package vm
 
 import (
 	"strconv"
-	"strings"
+	"unicode"
 )
 
 func ParseStatement(line string) (stmt Statement, err error) {
-	var before, after string
-	var found bool
-
-	before, after, found = strings.Cut(line, " ")
-
-	if stmt.Command, err = ParseCommand(before); !found || err != nil {
-		return
-	}
+	stmt, _, err = parseStatement(line)
+	return
+}
 
-	if before, after, found = strings.Cut(after, " "); !found {
+// parseStatement parses line like ParseStatement, and returns the offset in
+// line of the field an error is about.
+func parseStatement(line string) (stmt Statement, offset int, err error) {
+	fields, offsets := tokenize(line)
+	if len(fields) == 0 {
 		err = ErrStatementInvalid{stmt: line}
 		return
 	}
 
-	if stmt.Segment, err = ParseSegment(before); err != nil {
+	if stmt.Command, err = ParseCommand(fields[0]); err != nil {
 		return
 	}
 
-	var index uint64
-	if index, err = strconv.ParseUint(after, 10, 16); err != nil {
-		return
+	switch stmt.Command {
+	case CommandPush, CommandPop:
+		if len(fields) != 3 {
+			err = ErrStatementInvalid{stmt: line}
+			return
+		}
+		offset = offsets[1]
+		if stmt.Segment, err = ParseSegment(fields[1]); err != nil {
+			return
+		}
+		offset = offsets[2]
+		if stmt.Index, err = ParseIndex(fields[2]); err != nil {
+			return
+		}
+	case CommandLabel, CommandGoto, CommandIfGoto:
+		if len(fields) != 2 {
+			err = ErrStatementInvalid{stmt: line}
+			return
+		}
+		offset = offsets[1]
+		if stmt.Label, err = ParseLabel(fields[1]); err != nil {
+			return
+		}
+	case CommandFunction, CommandCall:
+		if len(fields) != 3 {
+			err = ErrStatementInvalid{stmt: line}
+			return
+		}
+		offset = offsets[1]
+		if stmt.Function, err = ParseFunction(fields[1]); err != nil {
+			return
+		}
+		offset = offsets[2]
+		if stmt.Count, err = ParseIndex(fields[2]); err != nil {
+			return
+		}
+	default:
+		if len(fields) != 1 {
+			err = ErrStatementInvalid{stmt: line}
+			return
+		}
 	}
-	stmt.Index = int16(index)
 
+	return stmt, 0, nil
+}
+
+// tokenize splits line into fields like strings.Fields, along with the
+// offset of each field in line.
+func tokenize(line string) (fields []string, offsets []int) {
+	start := -1
+	for idx, char := range line + " " {
+		switch {
+		case unicode.IsSpace(char) && start >= 0:
+			fields, offsets = append(fields, line[start:idx]), append(offsets, start)
+			start = -1
+		case !unicode.IsSpace(char) && start < 0:
+			start = idx
+		}
+	}
 	return
 }
 
@@ -52,3 +104,31 @@ func ParseSegment(str string) (seg Segment, err error) {
 	}
 	return
 }
+
+func ParseIndex(str string) (index int16, err error) {
+	var value uint64
+	if value, err = strconv.ParseUint(str, 10, 15); err != nil {
+		err = ErrIndexInvalid{index: str}
+		return
+	}
+	index = int16(value)
+	return
+}
+
+func ParseLabel(str string) (label string, err error) {
+	if !SymbolRegex.MatchString(str) {
+		err = ErrLabelInvalid{label: str}
+		return
+	}
+	label = str
+	return
+}
+
+func ParseFunction(str string) (function string, err error) {
+	if !SymbolRegex.MatchString(str) {
+		err = ErrFunctionInvalid{function: str}
+		return
+	}
+	function = str
+	return
+}
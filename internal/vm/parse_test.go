@@ -5,6 +5,8 @@
 package vm
 
 import (
+	"hack/internal/diag"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -41,3 +43,89 @@ func TestParseAddStatement(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, Statement{Command: CommandAdd}, stmt)
 }
+
+func TestParseLabelStatement(t *testing.T) {
+	t.Run("label", func(t *testing.T) {
+		stmt, err := ParseStatement("label LOOP")
+		assert.Nil(t, err)
+		assert.Equal(t, Statement{Command: CommandLabel, Label: "LOOP"}, stmt)
+	})
+
+	t.Run("goto", func(t *testing.T) {
+		stmt, err := ParseStatement("goto END")
+		assert.Nil(t, err)
+		assert.Equal(t, Statement{Command: CommandGoto, Label: "END"}, stmt)
+	})
+
+	t.Run("if-goto", func(t *testing.T) {
+		stmt, err := ParseStatement("if-goto COMPUTE_ELEMENT")
+		assert.Nil(t, err)
+		assert.Equal(t, Statement{Command: CommandIfGoto, Label: "COMPUTE_ELEMENT"}, stmt)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := ParseStatement("goto 1ABC")
+		assert.Equal(t, ErrLabelInvalid{label: "1ABC"}, err)
+	})
+}
+
+func TestParse(t *testing.T) {
+	prog, err := ParseString(`
+// comment
+	push argument 0     // inline comment
+label LOOP
+	if-goto LOOP
+`)
+	assert.Nil(t, err)
+	assert.Equal(t, Program{
+		{Command: CommandPush, Segment: SegmentArgument, Index: 0},
+		{Command: CommandLabel, Label: "LOOP"},
+		{Command: CommandIfGoto, Label: "LOOP"},
+	}, prog)
+}
+
+func TestParseFunctionStatement(t *testing.T) {
+	t.Run("function", func(t *testing.T) {
+		stmt, err := ParseStatement("function Main.fibonacci 2")
+		assert.Nil(t, err)
+		assert.Equal(t, Statement{Command: CommandFunction, Function: "Main.fibonacci", Count: 2}, stmt)
+	})
+
+	t.Run("call", func(t *testing.T) {
+		stmt, err := ParseStatement("call Main.fibonacci 1")
+		assert.Nil(t, err)
+		assert.Equal(t, Statement{Command: CommandCall, Function: "Main.fibonacci", Count: 1}, stmt)
+	})
+
+	t.Run("return", func(t *testing.T) {
+		stmt, err := ParseStatement("return")
+		assert.Nil(t, err)
+		assert.Equal(t, Statement{Command: CommandReturn}, stmt)
+	})
+}
+
+func TestParseError(t *testing.T) {
+	_, err := ParseFile("Bad.vm", strings.NewReader("push constant 1\n\tpush foo 1 // comment\n"), 0)
+	assert.Equal(t, diag.Diagnostic{
+		File:   "Bad.vm",
+		Line:   2,
+		Column: 7,
+		Source: "\tpush foo 1 // comment",
+		Err:    ErrSegmentInvalid{seg: "foo"},
+	}, err)
+}
+
+func TestParseErrorColumn(t *testing.T) {
+	_, err := ParseString("push  local  local")
+	assert.Equal(t, "1:14: invalid index: local", err.Error())
+	_, err = ParseString("call call call")
+	assert.Equal(t, "1:11: invalid index: call", err.Error())
+}
+
+func TestParseAllErrors(t *testing.T) {
+	_, err := ParseFile("Bad.vm", strings.NewReader("push foo 1\nadd\nlabel 1A\n"), AllErrors)
+	assert.Equal(t, diag.List{
+		{File: "Bad.vm", Line: 1, Column: 6, Source: "push foo 1", Err: ErrSegmentInvalid{seg: "foo"}},
+		{File: "Bad.vm", Line: 3, Column: 7, Source: "label 1A", Err: ErrLabelInvalid{label: "1A"}},
+	}, err)
+}
@@ -0,0 +1,108 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vm
+
+import (
+	"encoding/json"
+	"hack/internal/asm"
+	"io"
+	"sort"
+)
+
+type (
+	// SourceMap relates the statements of the translated VM files to the
+	// assembly and the machine code generated for them.
+	SourceMap struct {
+		Entries []SourceMapEntry `json:"entries"`
+	}
+
+	// SourceMapEntry maps one statement. Asm is the range of instruction
+	// indices, labels included, which are also the 0-based lines of the
+	// formatted assembly, and ROM the range of ROM addresses. Both ranges are
+	// half-open and empty for label statements.
+	SourceMapEntry struct {
+		File      string `json:"file"`
+		Line      int    `json:"line,omitempty"`
+		Statement string `json:"statement"`
+		Asm       [2]int `json:"asm"`
+		ROM       [2]int `json:"rom"`
+	}
+)
+
+// WriteJSON writes the source map as JSON, one entry per line.
+func (m *SourceMap) WriteJSON(w io.Writer) (err error) {
+	if _, err = io.WriteString(w, `{"entries": [`); err != nil {
+		return
+	}
+	for idx, entry := range m.Entries {
+		var data []byte
+		if data, err = json.Marshal(entry); err != nil {
+			return
+		}
+		sep := ",\n  "
+		if idx == 0 {
+			sep = "\n  "
+		}
+		if _, err = io.WriteString(w, sep+string(data)); err != nil {
+			return
+		}
+	}
+	_, err = io.WriteString(w, "\n]}\n")
+	return
+}
+
+// AtROM returns the entry of the statement the instruction at a ROM address
+// was translated from.
+func (m *SourceMap) AtROM(address int) (entry SourceMapEntry, ok bool) {
+	idx := sort.Search(len(m.Entries), func(idx int) bool {
+		return m.Entries[idx].ROM[1] > address
+	})
+	if idx == len(m.Entries) || m.Entries[idx].ROM[0] > address {
+		return
+	}
+	return m.Entries[idx], true
+}
+
+// Remap updates the ranges of the source map, built while translating to
+// prog, to the instructions of optimized, the result of prog.Optimize. An
+// instruction the optimization created belongs to the statement of the one
+// before it.
+func (m *SourceMap) Remap(prog, optimized asm.Program) {
+	entries := map[asm.Instruction]int{}
+	for idx, entry := range m.Entries {
+		for _, instr := range prog[entry.Asm[0]:entry.Asm[1]] {
+			if _, ok := entries[instr]; !ok {
+				entries[instr] = idx
+			}
+		}
+	}
+
+	// Instructions before the first entry, such as the bootstrap code, are
+	// counted in every range start.
+	var instrs, words int
+	current := -1
+	for _, instr := range optimized {
+		if idx, ok := entries[instr]; ok {
+			for ; current < idx; current++ {
+				if current >= 0 {
+					m.Entries[current].Asm[1], m.Entries[current].ROM[1] = instrs, words
+				}
+				m.Entries[current+1].Asm[0], m.Entries[current+1].ROM[0] = instrs, words
+			}
+		}
+		instrs += 1
+		if _, ok := instr.(*asm.LabelInstruction); !ok {
+			words += 1
+		}
+	}
+	for ; current < len(m.Entries); current++ {
+		if current >= 0 {
+			m.Entries[current].Asm[1], m.Entries[current].ROM[1] = instrs, words
+		}
+		if current+1 < len(m.Entries) {
+			m.Entries[current+1].Asm[0], m.Entries[current+1].ROM[0] = instrs, words
+		}
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vm
+
+import (
+	"hack/internal/asm"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSource(t *testing.T) {
+	prog, src, err := ParseSource("Main.vm", strings.NewReader("// comment\npush constant 1\r\n\nlabel L\n"), 0)
+	assert.Nil(t, err)
+	assert.Len(t, prog, 2)
+	assert.Equal(t, &Source{
+		File:      "Main.vm",
+		Lines:     []string{"// comment", "push constant 1", "", "label L"},
+		StmtLines: []int{2, 4},
+	}, src)
+}
+
+func TestSourceMap(t *testing.T) {
+	prog, src, err := ParseSource("Main.vm", strings.NewReader(`function Main.main 0
+push constant 7
+label LOOP
+goto LOOP
+`), 0)
+	assert.Nil(t, err)
+
+	sourceMap := &SourceMap{}
+	tr := &Translator{SourceMap: sourceMap}
+	instrs := tr.Bootstrap()
+	tr.SetFile("Main")
+	tr.Source = src
+	progInstrs, err := prog.Instructions(tr)
+	assert.Nil(t, err)
+	instrs = append(instrs, progInstrs...)
+
+	boot := len(tr.Bootstrap())
+	words := boot - 1 // the return label of the bootstrap call
+	assert.Equal(t, []SourceMapEntry{
+		{File: "Main.vm", Line: 1, Statement: "function Main.main 0", Asm: [2]int{boot, boot + 1}, ROM: [2]int{words, words}},
+		{File: "Main.vm", Line: 2, Statement: "push constant 7", Asm: [2]int{boot + 1, boot + 8}, ROM: [2]int{words, words + 7}},
+		{File: "Main.vm", Line: 3, Statement: "label LOOP", Asm: [2]int{boot + 8, boot + 9}, ROM: [2]int{words + 7, words + 7}},
+		{File: "Main.vm", Line: 4, Statement: "goto LOOP", Asm: [2]int{boot + 9, boot + 11}, ROM: [2]int{words + 7, words + 9}},
+	}, sourceMap.Entries)
+	assert.Len(t, instrs, boot+11)
+
+	// The ranges match the formatted assembly line for line.
+	text, err := asm.FormatString(instrs)
+	assert.Nil(t, err)
+	lines := strings.Split(text, "\n")
+	assert.Equal(t, []string{"@Main.main$LOOP", "0;JMP"}, lines[boot+9:boot+11])
+
+	entry, ok := sourceMap.AtROM(words + 8)
+	assert.True(t, ok)
+	assert.Equal(t, "goto LOOP", entry.Statement)
+	entry, ok = sourceMap.AtROM(words)
+	assert.True(t, ok)
+	assert.Equal(t, "push constant 7", entry.Statement)
+	_, ok = sourceMap.AtROM(0)
+	assert.False(t, ok)
+	_, ok = sourceMap.AtROM(words + 9)
+	assert.False(t, ok)
+
+	builder := strings.Builder{}
+	assert.Nil(t, (&SourceMap{Entries: sourceMap.Entries[2:3]}).WriteJSON(&builder))
+	assert.Equal(t, `{"entries": [
+  {"file":"Main.vm","line":3,"statement":"label LOOP","asm":[`+strconv.Itoa(boot+8)+`,`+strconv.Itoa(boot+9)+`],"rom":[`+strconv.Itoa(words+7)+`,`+strconv.Itoa(words+7)+`]}
+]}
+`, builder.String())
+}
+
+func TestSourceMapRemap(t *testing.T) {
+	prog, err := ParseString(`function Main.main 0
+push constant 7
+pop temp 0
+label LOOP
+goto LOOP`)
+	assert.Nil(t, err)
+
+	sourceMap := &SourceMap{}
+	tr := &Translator{SourceMap: sourceMap}
+	tr.SetFile("Main")
+	instrs, err := prog.Instructions(tr)
+	assert.Nil(t, err)
+	optimized, err := instrs.Optimize(asm.Rules...)
+	assert.Nil(t, err)
+	assert.Less(t, len(optimized), len(instrs))
+	sourceMap.Remap(instrs, optimized)
+
+	text, err := asm.FormatString(optimized)
+	assert.Nil(t, err)
+	lines := strings.Split(text, "\n")
+	var words int
+	assert.Equal(t, 0, sourceMap.Entries[0].Asm[0])
+	for _, entry := range sourceMap.Entries {
+		assert.Equal(t, words, entry.ROM[0], entry.Statement)
+		for _, line := range lines[entry.Asm[0]:entry.Asm[1]] {
+			if !strings.HasPrefix(line, "(") {
+				words += 1
+			}
+		}
+		assert.Equal(t, words, entry.ROM[1], entry.Statement)
+	}
+	assert.Equal(t, len(optimized), sourceMap.Entries[len(sourceMap.Entries)-1].Asm[1])
+	assert.Equal(t, []string{"@Main.main$LOOP", "0;JMP"}, lines[sourceMap.Entries[4].Asm[0]:sourceMap.Entries[4].Asm[1]])
+}
@@ -11,42 +11,448 @@ import (
 	"strings"
 )
 
-func (stmt Statement) TranslateString() (str string, err error) {
+type (
+	Translator struct {
+		File string
+
+		// Source, if set, is where the statements of the current file come
+		// from, and SourceMap, if set, receives an entry for each statement
+		// translated by Program.Instructions.
+		Source    *Source
+		SourceMap *SourceMap
+
+		function string
+		labels   int
+		// instrs and words count the instructions, and the ROM words among
+		// them, emitted by Bootstrap and Program.Instructions so far.
+		instrs int
+		words  int
+	}
+)
+
+// Bootstrap returns the startup code of a multi-file program: it points SP at
+// the stack base and calls Sys.init.
+func (t *Translator) Bootstrap() asm.Program {
+	prog := asm.Program{
+		&asm.AddressInstructionConstant{Address: StackBase},
+		&asm.ComputeInstruction{Dest: asm.DestD, Comp: asm.Comp0A},
+		&asm.AddressInstructionSymbol{Symbol: asm.SymbolSP},
+		&asm.ComputeInstruction{Dest: asm.DestM, Comp: asm.Comp0D},
+	}
+	prog = append(prog, call(BootstrapFunction, 0, t.returnLabel())...)
+	t.count(prog)
+	return prog
+}
+
+// SetFile starts the translation of the file named name, scoping subsequent
+// static variables to it.
+func (t *Translator) SetFile(name string) {
+	t.File = name
+	t.Source = nil
+	t.function = ""
+}
+
+// count advances the instruction and ROM word counters past prog.
+func (t *Translator) count(prog asm.Program) {
+	for _, instr := range prog {
+		if _, ok := instr.(*asm.LabelInstruction); !ok {
+			t.words += 1
+		}
+	}
+	t.instrs += len(prog)
+}
+
+// record adds the instructions translated from the idx-th statement of the
+// current file to the source map.
+func (t *Translator) record(stmt Statement, idx int, prog asm.Program) {
+	instrs, words := t.instrs, t.words
+	t.count(prog)
+	if t.SourceMap == nil {
+		return
+	}
+
+	entry := SourceMapEntry{
+		File:      t.File,
+		Statement: stmt.String(),
+		Asm:       [2]int{instrs, t.instrs},
+		ROM:       [2]int{words, t.words},
+	}
+	if t.Source != nil && idx < len(t.Source.StmtLines) {
+		entry.File, entry.Line = t.Source.File, t.Source.StmtLines[idx]
+	}
+	t.SourceMap.Entries = append(t.SourceMap.Entries, entry)
+}
+
+func (t *Translator) returnLabel() string {
+	if t.function == "" {
+		return t.label("RET")
+	}
+	// SymbolRegex rejects $ in VM labels, so no label statement can produce
+	// the same name.
+	label := t.function + "$ret$" + strconv.Itoa(t.labels)
+	t.labels += 1
+	return label
+}
+
+func (t *Translator) scope(label string) string {
+	if t.function == "" {
+		return label
+	}
+	return t.function + "$" + label
+}
+
+func (t *Translator) label(prefix string) string {
+	label := "$" + prefix + "." + strconv.Itoa(t.labels)
+	t.labels += 1
+	return label
+}
+
+func (stmt Statement) TranslateString(t *Translator) (str string, err error) {
 	builder := strings.Builder{}
-	err = stmt.Translate(&builder)
+	err = stmt.Translate(&builder, t)
 	str = builder.String()
 	return
 }
 
-func (stmt Statement) Translate(w io.Writer) (err error) {
+func (stmt Statement) Translate(w io.Writer, t *Translator) (err error) {
+	var prog asm.Program
+	if prog, err = stmt.Instructions(t); err != nil {
+		return
+	}
+	return prog.Format(w)
+}
+
+func (stmt Statement) Instructions(t *Translator) (prog asm.Program, err error) {
 	switch stmt.Command {
 	case CommandPush:
-		switch stmt.Segment {
-		case SegmentConstant:
-			asm.Program{
-				&asm.AddressInstructionConstant{Address: stmt.Index},
-				&asm.ComputeInstruction{Dest: asm.DestD, Comp: asm.Comp0A},
-				&asm.AddressInstructionSymbol{Symbol: asm.SymbolSP},
-				&asm.ComputeInstruction{Dest: asm.DestA, Comp: asm.Comp1M},
-				&asm.ComputeInstruction{Dest: asm.DestM, Comp: asm.Comp0D},
-				&asm.AddressInstructionSymbol{Symbol: asm.SymbolSP},
-				&asm.ComputeInstruction{Dest: asm.DestM, Comp: asm.Comp1MPlus1},
-			}.Format(w)
-		default:
-			panic("unhandled segment for CommandPush: " + strconv.Itoa(int(stmt.Segment)))
+		if prog, err = stmt.load(t); err != nil {
+			return
 		}
+		prog = append(prog, pushD()...)
+	case CommandPop:
+		prog, err = stmt.store(t)
 	case CommandAdd:
-		asm.Program{
-			&asm.AddressInstructionSymbol{Symbol: asm.SymbolSP},
-			&asm.ComputeInstruction{Dest: asm.DestA | asm.DestM, Comp: asm.Comp1MMinus1},
+		prog = binary(asm.Comp1DPlusM)
+	case CommandSub:
+		prog = binary(asm.Comp1MMinusD)
+	case CommandAnd:
+		prog = binary(asm.Comp1DAndM)
+	case CommandOr:
+		prog = binary(asm.Comp1DOrM)
+	case CommandNeg:
+		prog = unary(asm.Comp1NegM)
+	case CommandNot:
+		prog = unary(asm.Comp1NotM)
+	case CommandEq:
+		prog = compare(t.label("EQ"), asm.JumpJEQ)
+	case CommandGt:
+		prog = compareOrdered(t.label("GT"), asm.JumpJGT)
+	case CommandLt:
+		prog = compareOrdered(t.label("LT"), asm.JumpJLT)
+	case CommandLabel:
+		prog = asm.Program{
+			&asm.LabelInstruction{Symbol: t.scope(stmt.Label)},
+		}
+	case CommandGoto:
+		prog = asm.Program{
+			&asm.AddressInstructionSymbol{Symbol: t.scope(stmt.Label)},
+			&asm.ComputeInstruction{Comp: asm.Comp00, Jump: asm.JumpJMP},
+		}
+	case CommandIfGoto:
+		prog = append(popD(),
+			&asm.AddressInstructionSymbol{Symbol: t.scope(stmt.Label)},
+			&asm.ComputeInstruction{Comp: asm.Comp0D, Jump: asm.JumpJNE},
+		)
+	case CommandFunction:
+		t.function = stmt.Function
+		prog = function(stmt.Function, stmt.Count)
+	case CommandCall:
+		prog = call(stmt.Function, stmt.Count, t.returnLabel())
+	case CommandReturn:
+		prog = ret()
+	default:
+		err = ErrUnhandledCommand{cmd: stmt.Command}
+	}
+
+	return
+}
+
+// load sets D to the value of the statement's segment at its index.
+func (stmt Statement) load(t *Translator) (prog asm.Program, err error) {
+	switch stmt.Segment {
+	case SegmentConstant:
+		prog = asm.Program{
+			&asm.AddressInstructionConstant{Address: stmt.Index},
+			&asm.ComputeInstruction{Dest: asm.DestD, Comp: asm.Comp0A},
+		}
+	case SegmentArgument, SegmentLocal, SegmentThis, SegmentThat:
+		prog = asm.Program{
+			&asm.AddressInstructionConstant{Address: stmt.Index},
+			&asm.ComputeInstruction{Dest: asm.DestD, Comp: asm.Comp0A},
+			&asm.AddressInstructionSymbol{Symbol: SegmentToSymbol[stmt.Segment]},
+			&asm.ComputeInstruction{Dest: asm.DestA, Comp: asm.Comp1DPlusM},
 			&asm.ComputeInstruction{Dest: asm.DestD, Comp: asm.Comp1M},
-			&asm.AddressInstructionSymbol{Symbol: asm.SymbolSP},
-			&asm.ComputeInstruction{Dest: asm.DestA, Comp: asm.Comp1MMinus1},
-			&asm.ComputeInstruction{Dest: asm.DestM, Comp: asm.Comp1DPlusM},
-		}.Format(w)
+		}
+	case SegmentStatic, SegmentPointer, SegmentTemp:
+		var addr asm.Instruction
+		if addr, err = stmt.address(t); err != nil {
+			return
+		}
+		prog = asm.Program{
+			addr,
+			&asm.ComputeInstruction{Dest: asm.DestD, Comp: asm.Comp1M},
+		}
 	default:
-		panic("unhandled command: " + strconv.Itoa(int(stmt.Command)))
+		err = ErrUnhandledSegment{cmd: stmt.Command, seg: stmt.Segment}
 	}
+	return
+}
 
+// store pops the topmost value into the statement's segment at its index.
+func (stmt Statement) store(t *Translator) (prog asm.Program, err error) {
+	switch stmt.Segment {
+	case SegmentArgument, SegmentLocal, SegmentThis, SegmentThat:
+		prog = append(asm.Program{
+			&asm.AddressInstructionConstant{Address: stmt.Index},
+			&asm.ComputeInstruction{Dest: asm.DestD, Comp: asm.Comp0A},
+			&asm.AddressInstructionSymbol{Symbol: SegmentToSymbol[stmt.Segment]},
+			&asm.ComputeInstruction{Dest: asm.DestD, Comp: asm.Comp1DPlusM},
+			&asm.AddressInstructionSymbol{Symbol: asm.SymbolR13},
+			&asm.ComputeInstruction{Dest: asm.DestM, Comp: asm.Comp0D},
+		}, append(popD(),
+			&asm.AddressInstructionSymbol{Symbol: asm.SymbolR13},
+			&asm.ComputeInstruction{Dest: asm.DestA, Comp: asm.Comp1M},
+			&asm.ComputeInstruction{Dest: asm.DestM, Comp: asm.Comp0D},
+		)...)
+	case SegmentStatic, SegmentPointer, SegmentTemp:
+		var addr asm.Instruction
+		if addr, err = stmt.address(t); err != nil {
+			return
+		}
+		prog = append(popD(),
+			addr,
+			&asm.ComputeInstruction{Dest: asm.DestM, Comp: asm.Comp0D},
+		)
+	default:
+		err = ErrUnhandledSegment{cmd: stmt.Command, seg: stmt.Segment}
+	}
+	return
+}
+
+// address returns the address instruction of a fixed-location segment entry.
+func (stmt Statement) address(t *Translator) (instr asm.Instruction, err error) {
+	switch stmt.Segment {
+	case SegmentStatic:
+		instr = &asm.AddressInstructionSymbol{Symbol: t.File + "." + strconv.Itoa(int(stmt.Index))}
+	case SegmentPointer:
+		if stmt.Index >= PointerSize {
+			err = ErrIndexOutOfRange{seg: stmt.Segment, index: stmt.Index}
+			return
+		}
+		instr = &asm.AddressInstructionConstant{Address: PointerBase + stmt.Index}
+	case SegmentTemp:
+		if stmt.Index >= TempSize {
+			err = ErrIndexOutOfRange{seg: stmt.Segment, index: stmt.Index}
+			return
+		}
+		instr = &asm.AddressInstructionConstant{Address: TempBase + stmt.Index}
+	default:
+		err = ErrUnhandledSegment{cmd: stmt.Command, seg: stmt.Segment}
+	}
 	return
 }
+
+// function declares name as an entry point and zeroes its locals local
+// variables on the stack.
+func function(name string, locals int16) asm.Program {
+	prog := asm.Program{
+		&asm.LabelInstruction{Symbol: name},
+	}
+	if locals == 0 {
+		return prog
+	}
+
+	prog = append(prog,
+		&asm.AddressInstructionSymbol{Symbol: asm.SymbolSP},
+		&asm.ComputeInstruction{Dest: asm.DestA, Comp: asm.Comp1M},
+	)
+	for range locals {
+		prog = append(prog,
+			&asm.ComputeInstruction{Dest: asm.DestM, Comp: asm.Comp00},
+			&asm.ComputeInstruction{Dest: asm.DestA, Comp: asm.Comp0APlus1},
+		)
+	}
+	return append(prog,
+		&asm.ComputeInstruction{Dest: asm.DestD, Comp: asm.Comp0A},
+		&asm.AddressInstructionSymbol{Symbol: asm.SymbolSP},
+		&asm.ComputeInstruction{Dest: asm.DestM, Comp: asm.Comp0D},
+	)
+}
+
+// call saves the caller's frame, repositions ARG and LCL for the callee and
+// jumps to name, resuming at label once the callee returns.
+func call(name string, args int16, label string) asm.Program {
+	prog := asm.Program{
+		&asm.AddressInstructionSymbol{Symbol: label},
+		&asm.ComputeInstruction{Dest: asm.DestD, Comp: asm.Comp0A},
+	}
+	prog = append(prog, pushD()...)
+	for _, symbol := range []string{asm.SymbolLCL, asm.SymbolARG, asm.SymbolTHIS, asm.SymbolTHAT} {
+		prog = append(prog,
+			&asm.AddressInstructionSymbol{Symbol: symbol},
+			&asm.ComputeInstruction{Dest: asm.DestD, Comp: asm.Comp1M},
+		)
+		prog = append(prog, pushD()...)
+	}
+	return append(prog,
+		&asm.AddressInstructionSymbol{Symbol: asm.SymbolSP},
+		&asm.ComputeInstruction{Dest: asm.DestD, Comp: asm.Comp1M},
+		&asm.AddressInstructionSymbol{Symbol: asm.SymbolLCL},
+		&asm.ComputeInstruction{Dest: asm.DestM, Comp: asm.Comp0D},
+		&asm.AddressInstructionConstant{Address: FrameSize + args},
+		&asm.ComputeInstruction{Dest: asm.DestD, Comp: asm.Comp0DMinusA},
+		&asm.AddressInstructionSymbol{Symbol: asm.SymbolARG},
+		&asm.ComputeInstruction{Dest: asm.DestM, Comp: asm.Comp0D},
+		&asm.AddressInstructionSymbol{Symbol: name},
+		&asm.ComputeInstruction{Comp: asm.Comp00, Jump: asm.JumpJMP},
+		&asm.LabelInstruction{Symbol: label},
+	)
+}
+
+// ret copies the return value to the caller's stack top, restores the
+// caller's frame and jumps back to the return address.
+func ret() asm.Program {
+	prog := asm.Program{
+		&asm.AddressInstructionSymbol{Symbol: asm.SymbolLCL},
+		&asm.ComputeInstruction{Dest: asm.DestD, Comp: asm.Comp1M},
+		&asm.AddressInstructionSymbol{Symbol: asm.SymbolR13},
+		&asm.ComputeInstruction{Dest: asm.DestM, Comp: asm.Comp0D},
+		&asm.AddressInstructionConstant{Address: FrameSize},
+		&asm.ComputeInstruction{Dest: asm.DestA, Comp: asm.Comp0DMinusA},
+		&asm.ComputeInstruction{Dest: asm.DestD, Comp: asm.Comp1M},
+		&asm.AddressInstructionSymbol{Symbol: asm.SymbolR14},
+		&asm.ComputeInstruction{Dest: asm.DestM, Comp: asm.Comp0D},
+	}
+	prog = append(prog, popD()...)
+	prog = append(prog,
+		&asm.AddressInstructionSymbol{Symbol: asm.SymbolARG},
+		&asm.ComputeInstruction{Dest: asm.DestA, Comp: asm.Comp1M},
+		&asm.ComputeInstruction{Dest: asm.DestM, Comp: asm.Comp0D},
+		&asm.ComputeInstruction{Dest: asm.DestD, Comp: asm.Comp0APlus1},
+		&asm.AddressInstructionSymbol{Symbol: asm.SymbolSP},
+		&asm.ComputeInstruction{Dest: asm.DestM, Comp: asm.Comp0D},
+	)
+	for _, symbol := range []string{asm.SymbolTHAT, asm.SymbolTHIS, asm.SymbolARG, asm.SymbolLCL} {
+		prog = append(prog,
+			&asm.AddressInstructionSymbol{Symbol: asm.SymbolR13},
+			&asm.ComputeInstruction{Dest: asm.DestA | asm.DestM, Comp: asm.Comp1MMinus1},
+			&asm.ComputeInstruction{Dest: asm.DestD, Comp: asm.Comp1M},
+			&asm.AddressInstructionSymbol{Symbol: symbol},
+			&asm.ComputeInstruction{Dest: asm.DestM, Comp: asm.Comp0D},
+		)
+	}
+	return append(prog,
+		&asm.AddressInstructionSymbol{Symbol: asm.SymbolR14},
+		&asm.ComputeInstruction{Dest: asm.DestA, Comp: asm.Comp1M},
+		&asm.ComputeInstruction{Comp: asm.Comp00, Jump: asm.JumpJMP},
+	)
+}
+
+// pushD pushes the D register onto the stack.
+func pushD() asm.Program {
+	return asm.Program{
+		&asm.AddressInstructionSymbol{Symbol: asm.SymbolSP},
+		&asm.ComputeInstruction{Dest: asm.DestA, Comp: asm.Comp1M},
+		&asm.ComputeInstruction{Dest: asm.DestM, Comp: asm.Comp0D},
+		&asm.AddressInstructionSymbol{Symbol: asm.SymbolSP},
+		&asm.ComputeInstruction{Dest: asm.DestM, Comp: asm.Comp1MPlus1},
+	}
+}
+
+// popD pops the top of the stack into the D register, leaving A pointing at
+// the new top of the stack.
+func popD() asm.Program {
+	return asm.Program{
+		&asm.AddressInstructionSymbol{Symbol: asm.SymbolSP},
+		&asm.ComputeInstruction{Dest: asm.DestA | asm.DestM, Comp: asm.Comp1MMinus1},
+		&asm.ComputeInstruction{Dest: asm.DestD, Comp: asm.Comp1M},
+	}
+}
+
+// binary replaces the two topmost values x and y with comp, where M holds x
+// and D holds y.
+func binary(comp asm.Comp) asm.Program {
+	return append(popD(),
+		&asm.ComputeInstruction{Dest: asm.DestA, Comp: asm.Comp0AMinus1},
+		&asm.ComputeInstruction{Dest: asm.DestM, Comp: comp},
+	)
+}
+
+// unary replaces the topmost value with comp, where M holds the value.
+func unary(comp asm.Comp) asm.Program {
+	return asm.Program{
+		&asm.AddressInstructionSymbol{Symbol: asm.SymbolSP},
+		&asm.ComputeInstruction{Dest: asm.DestA, Comp: asm.Comp1MMinus1},
+		&asm.ComputeInstruction{Dest: asm.DestM, Comp: comp},
+	}
+}
+
+// compare replaces the two topmost values x and y with -1 (true) if x-y
+// satisfies jump, or 0 (false) otherwise. label must be unique within the
+// translated program.
+func compare(label string, jump asm.Jump) asm.Program {
+	return append(popD(),
+		&asm.ComputeInstruction{Dest: asm.DestA, Comp: asm.Comp0AMinus1},
+		&asm.ComputeInstruction{Dest: asm.DestD, Comp: asm.Comp1MMinusD},
+		&asm.ComputeInstruction{Dest: asm.DestM, Comp: asm.Comp0Neg1},
+		&asm.AddressInstructionSymbol{Symbol: label},
+		&asm.ComputeInstruction{Comp: asm.Comp0D, Jump: jump},
+		&asm.AddressInstructionSymbol{Symbol: asm.SymbolSP},
+		&asm.ComputeInstruction{Dest: asm.DestA, Comp: asm.Comp1MMinus1},
+		&asm.ComputeInstruction{Dest: asm.DestM, Comp: asm.Comp00},
+		&asm.LabelInstruction{Symbol: label},
+	)
+}
+
+// compareOrdered is compare for gt and lt, where x-y may overflow: operands
+// of different signs are ordered by the sign of x alone.
+func compareOrdered(label string, jump asm.Jump) asm.Program {
+	yNeg, sub, cmp := label+".YNEG", label+".SUB", label+".CMP"
+	prog := append(popD(),
+		&asm.AddressInstructionSymbol{Symbol: yNeg},
+		&asm.ComputeInstruction{Comp: asm.Comp0D, Jump: asm.JumpJLT},
+		&asm.AddressInstructionSymbol{Symbol: asm.SymbolSP},
+		&asm.ComputeInstruction{Dest: asm.DestA, Comp: asm.Comp1MMinus1},
+		&asm.ComputeInstruction{Dest: asm.DestD, Comp: asm.Comp1M},
+		&asm.AddressInstructionSymbol{Symbol: cmp},
+		&asm.ComputeInstruction{Comp: asm.Comp0D, Jump: asm.JumpJLT},
+		&asm.AddressInstructionSymbol{Symbol: sub},
+		&asm.ComputeInstruction{Comp: asm.Comp00, Jump: asm.JumpJMP},
+		&asm.LabelInstruction{Symbol: yNeg},
+		&asm.AddressInstructionSymbol{Symbol: asm.SymbolSP},
+		&asm.ComputeInstruction{Dest: asm.DestA, Comp: asm.Comp1MMinus1},
+		&asm.ComputeInstruction{Dest: asm.DestD, Comp: asm.Comp1M},
+		&asm.AddressInstructionSymbol{Symbol: sub},
+		&asm.ComputeInstruction{Comp: asm.Comp0D, Jump: asm.JumpJLT},
+		&asm.ComputeInstruction{Dest: asm.DestD, Comp: asm.Comp01},
+		&asm.AddressInstructionSymbol{Symbol: cmp},
+		&asm.ComputeInstruction{Comp: asm.Comp00, Jump: asm.JumpJMP},
+		&asm.LabelInstruction{Symbol: sub},
+		&asm.AddressInstructionSymbol{Symbol: asm.SymbolSP},
+		&asm.ComputeInstruction{Dest: asm.DestA, Comp: asm.Comp1M},
+		&asm.ComputeInstruction{Dest: asm.DestD, Comp: asm.Comp1M},
+		&asm.ComputeInstruction{Dest: asm.DestA, Comp: asm.Comp0AMinus1},
+		&asm.ComputeInstruction{Dest: asm.DestD, Comp: asm.Comp1MMinusD},
+		&asm.LabelInstruction{Symbol: cmp},
+		&asm.AddressInstructionSymbol{Symbol: asm.SymbolSP},
+		&asm.ComputeInstruction{Dest: asm.DestA, Comp: asm.Comp1MMinus1},
+		&asm.ComputeInstruction{Dest: asm.DestM, Comp: asm.Comp0Neg1},
+		&asm.AddressInstructionSymbol{Symbol: label},
+		&asm.ComputeInstruction{Comp: asm.Comp0D, Jump: jump},
+		&asm.AddressInstructionSymbol{Symbol: asm.SymbolSP},
+		&asm.ComputeInstruction{Dest: asm.DestA, Comp: asm.Comp1MMinus1},
+		&asm.ComputeInstruction{Dest: asm.DestM, Comp: asm.Comp00},
+		&asm.LabelInstruction{Symbol: label},
+	)
+	return prog
+}
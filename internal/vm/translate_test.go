@@ -0,0 +1,272 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vm
+
+import (
+	"hack/internal/asm"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTranslatePushConstant(t *testing.T) {
+	str, err := Statement{Command: CommandPush, Segment: SegmentConstant, Index: 7}.TranslateString(&Translator{})
+	assert.Nil(t, err)
+	assert.Equal(t, strings.Trim(`
+@7
+D=A
+@SP
+A=M
+M=D
+@SP
+M=M+1
+`, " \t\n\r"), str)
+}
+
+func TestTranslateSub(t *testing.T) {
+	str, err := Statement{Command: CommandSub}.TranslateString(&Translator{})
+	assert.Nil(t, err)
+	assert.Equal(t, strings.Trim(`
+@SP
+AM=M-1
+D=M
+A=A-1
+M=M-D
+`, " \t\n\r"), str)
+}
+
+func TestTranslateNot(t *testing.T) {
+	str, err := Statement{Command: CommandNot}.TranslateString(&Translator{})
+	assert.Nil(t, err)
+	assert.Equal(t, strings.Trim(`
+@SP
+A=M-1
+M=!M
+`, " \t\n\r"), str)
+}
+
+func TestTranslateCompare(t *testing.T) {
+	prog, err := ParseString(`
+eq
+lt
+`)
+	assert.Nil(t, err)
+
+	str, err := prog.TranslateString()
+	assert.Nil(t, err)
+	assert.Equal(t, strings.Trim(`
+@SP
+AM=M-1
+D=M
+A=A-1
+D=M-D
+M=-1
+@$EQ.0
+D;JEQ
+@SP
+A=M-1
+M=0
+($EQ.0)
+@SP
+AM=M-1
+D=M
+@$LT.1.YNEG
+D;JLT
+@SP
+A=M-1
+D=M
+@$LT.1.CMP
+D;JLT
+@$LT.1.SUB
+0;JMP
+($LT.1.YNEG)
+@SP
+A=M-1
+D=M
+@$LT.1.SUB
+D;JLT
+D=1
+@$LT.1.CMP
+0;JMP
+($LT.1.SUB)
+@SP
+A=M
+D=M
+A=A-1
+D=M-D
+($LT.1.CMP)
+@SP
+A=M-1
+M=-1
+@$LT.1
+D;JLT
+@SP
+A=M-1
+M=0
+($LT.1)
+`, " \t\n\r"), str)
+}
+
+func TestTranslatePopLocal(t *testing.T) {
+	str, err := Statement{Command: CommandPop, Segment: SegmentLocal, Index: 2}.TranslateString(&Translator{})
+	assert.Nil(t, err)
+	assert.Equal(t, strings.Trim(`
+@2
+D=A
+@LCL
+D=D+M
+@R13
+M=D
+@SP
+AM=M-1
+D=M
+@R13
+A=M
+M=D
+`, " \t\n\r"), str)
+}
+
+func TestTranslatePushTemp(t *testing.T) {
+	str, err := Statement{Command: CommandPush, Segment: SegmentTemp, Index: 3}.TranslateString(&Translator{})
+	assert.Nil(t, err)
+	assert.Equal(t, strings.Trim(`
+@8
+D=M
+@SP
+A=M
+M=D
+@SP
+M=M+1
+`, " \t\n\r"), str)
+}
+
+func TestTranslatePopStatic(t *testing.T) {
+	str, err := Statement{Command: CommandPop, Segment: SegmentStatic, Index: 4}.TranslateString(&Translator{File: "Foo"})
+	assert.Nil(t, err)
+	assert.Equal(t, strings.Trim(`
+@SP
+AM=M-1
+D=M
+@Foo.4
+M=D
+`, " \t\n\r"), str)
+}
+
+func TestTranslateIfGoto(t *testing.T) {
+	str, err := Statement{Command: CommandIfGoto, Label: "LOOP"}.TranslateString(&Translator{function: "Main.main"})
+	assert.Nil(t, err)
+	assert.Equal(t, strings.Trim(`
+@SP
+AM=M-1
+D=M
+@Main.main$LOOP
+D;JNE
+`, " \t\n\r"), str)
+}
+
+func TestTranslateFunction(t *testing.T) {
+	prog, err := ParseString(`
+function Main.main 2
+label LOOP
+goto LOOP
+`)
+	assert.Nil(t, err)
+
+	str, err := prog.TranslateString()
+	assert.Nil(t, err)
+	assert.Equal(t, strings.Trim(`
+(Main.main)
+@SP
+A=M
+M=0
+A=A+1
+M=0
+A=A+1
+D=A
+@SP
+M=D
+(Main.main$LOOP)
+@Main.main$LOOP
+0;JMP
+`, " \t\n\r"), str)
+}
+
+func TestTranslateCallReturnLabels(t *testing.T) {
+	prog, err := ParseString(`
+function Main.main 0
+call Main.f 0
+call Main.f 0
+`)
+	assert.Nil(t, err)
+
+	instrs, err := prog.Instructions(&Translator{})
+	assert.Nil(t, err)
+
+	var labels []string
+	for _, instr := range instrs {
+		if label, ok := instr.(*asm.LabelInstruction); ok {
+			labels = append(labels, label.Symbol)
+		}
+	}
+	assert.Equal(t, []string{"Main.main", "Main.main$ret$0", "Main.main$ret$1"}, labels)
+}
+
+func TestTranslateReturnLabelCollision(t *testing.T) {
+	prog, err := ParseString(`
+function Main.main 0
+label ret.0
+call Main.f 0
+`)
+	assert.Nil(t, err)
+
+	instrs, err := prog.Instructions(&Translator{})
+	assert.Nil(t, err)
+
+	var labels []string
+	for _, instr := range instrs {
+		if label, ok := instr.(*asm.LabelInstruction); ok {
+			labels = append(labels, label.Symbol)
+		}
+	}
+	assert.Equal(t, []string{"Main.main", "Main.main$ret.0", "Main.main$ret$0"}, labels)
+}
+
+func TestTranslateBootstrap(t *testing.T) {
+	str, err := asm.FormatString((&Translator{}).Bootstrap())
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(str, strings.Trim(`
+@256
+D=A
+@SP
+M=D
+@$RET.0
+D=A
+`, " \t\n\r")))
+	assert.True(t, strings.HasSuffix(str, strings.Trim(`
+@Sys.init
+0;JMP
+($RET.0)
+`, " \t\n\r")))
+}
+
+func TestTranslateError(t *testing.T) {
+	t.Run("pop constant", func(t *testing.T) {
+		_, err := Statement{Command: CommandPop, Segment: SegmentConstant, Index: 1}.TranslateString(&Translator{})
+		assert.Equal(t, ErrUnhandledSegment{cmd: CommandPop, seg: SegmentConstant}, err)
+		assert.Equal(t, "unhandled segment for pop: constant", err.Error())
+	})
+
+	t.Run("temp out of range", func(t *testing.T) {
+		_, err := Statement{Command: CommandPush, Segment: SegmentTemp, Index: 8}.TranslateString(&Translator{})
+		assert.Equal(t, ErrIndexOutOfRange{seg: SegmentTemp, index: 8}, err)
+	})
+
+	t.Run("command", func(t *testing.T) {
+		_, err := Program{{Command: Command(-1)}}.TranslateString()
+		assert.Equal(t, ErrUnhandledCommand{cmd: Command(-1)}, err)
+		assert.Equal(t, "unhandled command: Command(-1)", err.Error())
+	})
+}
@@ -6,6 +6,8 @@ package vm
 
 import (
 	"bufio"
+	"hack/internal/asm"
+	"hack/internal/diag"
 	"io"
 	"strings"
 )
@@ -19,25 +21,58 @@ func ParseString(str string) (prog Program, err error) {
 }
 
 func Parse(r io.Reader) (prog Program, err error) {
-	var line string
+	return ParseFile("", r, 0)
+}
+
+func ParseFile(name string, r io.Reader, mode Mode) (prog Program, err error) {
+	prog, _, err = ParseSource(name, r, mode)
+	return
+}
+
+// ParseSource parses r like ParseFile and also returns where each statement
+// of prog comes from.
+func ParseSource(name string, r io.Reader, mode Mode) (prog Program, src *Source, err error) {
+	var diags diag.List
+	var raw, line string
 	var stmt Statement
 
+	src = &Source{File: name}
 	s := bufio.NewScanner(r)
 
-	for s.Scan() {
-		line = strings.Trim(s.Text(), " \t\r")
-		if line == "" || strings.HasPrefix(line, "//") {
+	for lineno := 1; s.Scan(); lineno++ {
+		raw = strings.TrimRight(s.Text(), "\r")
+		src.Lines = append(src.Lines, raw)
+		line, _, _ = strings.Cut(raw, "//")
+		line = strings.Trim(line, " \t")
+		if line == "" {
 			continue
 		}
 
-		if stmt, err = ParseStatement(line); err != nil {
-			return
+		var offset int
+		if stmt, offset, err = parseStatement(line); err != nil {
+			indent := len(raw) - len(strings.TrimLeft(raw, " \t"))
+			diags = append(diags, diag.Diagnostic{
+				File:   name,
+				Line:   lineno,
+				Column: indent + offset + 1,
+				Source: raw,
+				Err:    err,
+			})
+			if mode&AllErrors == 0 {
+				err = diags[0]
+				return
+			}
+			continue
 		}
 
 		prog = append(prog, stmt)
+		src.StmtLines = append(src.StmtLines, lineno)
 	}
 
-	err = s.Err()
+	if err = s.Err(); err != nil {
+		return
+	}
+	err = diags.Err()
 	return
 }
 
@@ -49,13 +84,21 @@ func (prog Program) TranslateString() (str string, err error) {
 }
 
 func (prog Program) Translate(w io.Writer) (err error) {
+	var instrs asm.Program
+	if instrs, err = prog.Instructions(&Translator{}); err != nil {
+		return
+	}
+	return instrs.Format(w)
+}
+
+func (prog Program) Instructions(t *Translator) (instrs asm.Program, err error) {
+	var stmtInstrs asm.Program
 	for idx, stmt := range prog {
-		if idx > 0 {
-			if _, err = w.Write([]byte{'\n'}); err != nil {
-				return
-			}
+		if stmtInstrs, err = stmt.Instructions(t); err != nil {
+			return
 		}
-		stmt.Translate(w)
+		t.record(stmt, idx, stmtInstrs)
+		instrs = append(instrs, stmtInstrs...)
 	}
 	return
 }
@@ -0,0 +1,561 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vme
+
+import (
+	"bufio"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type block struct {
+	addr int
+	size int
+}
+
+// Builtins implements the Jack OS. Strings are laid out in the heap as
+// [maxLength, length, chars...]; Output writes text to Machine.Stdout and the
+// Keyboard read functions consume Machine.Stdin.
+var Builtins = map[string]Builtin{
+	"Math.init":     void,
+	"Math.abs":      mathAbs,
+	"Math.multiply": mathMultiply,
+	"Math.divide":   mathDivide,
+	"Math.min":      mathMin,
+	"Math.max":      mathMax,
+	"Math.sqrt":     mathSqrt,
+
+	"Memory.init":    void,
+	"Memory.peek":    memoryPeek,
+	"Memory.poke":    memoryPoke,
+	"Memory.alloc":   memoryAlloc,
+	"Memory.deAlloc": memoryDeAlloc,
+
+	"Array.new":     memoryAlloc,
+	"Array.dispose": memoryDeAlloc,
+
+	"String.new":           stringNew,
+	"String.dispose":       memoryDeAlloc,
+	"String.length":        stringLength,
+	"String.charAt":        stringCharAt,
+	"String.setCharAt":     stringSetCharAt,
+	"String.appendChar":    stringAppendChar,
+	"String.eraseLastChar": stringEraseLastChar,
+	"String.intValue":      stringIntValue,
+	"String.setInt":        stringSetInt,
+	"String.newLine":       constant(KeyNewLine),
+	"String.backSpace":     constant(KeyBackSpace),
+	"String.doubleQuote":   constant(KeyDoubleQuote),
+
+	"Output.init":        void,
+	"Output.moveCursor":  void,
+	"Output.printChar":   outputPrintChar,
+	"Output.printString": outputPrintString,
+	"Output.printInt":    outputPrintInt,
+	"Output.println":     outputPrintln,
+	"Output.backSpace":   outputBackSpace,
+
+	"Screen.init":          void,
+	"Screen.clearScreen":   screenClear,
+	"Screen.setColor":      screenSetColor,
+	"Screen.drawPixel":     screenDrawPixel,
+	"Screen.drawLine":      screenDrawLine,
+	"Screen.drawRectangle": screenDrawRectangle,
+	"Screen.drawCircle":    screenDrawCircle,
+
+	"Keyboard.init":       void,
+	"Keyboard.keyPressed": keyboardKeyPressed,
+	"Keyboard.readChar":   keyboardReadChar,
+	"Keyboard.readLine":   keyboardReadLine,
+	"Keyboard.readInt":    keyboardReadInt,
+
+	"Sys.halt":  sysHalt,
+	"Sys.error": sysError,
+	"Sys.wait":  void,
+}
+
+func void(m *Machine, args []int16) (int16, error) {
+	return 0, nil
+}
+
+func constant(value int16) Builtin {
+	return func(m *Machine, args []int16) (int16, error) {
+		return value, nil
+	}
+}
+
+func arg(args []int16, i int) int16 {
+	if i < len(args) {
+		return args[i]
+	}
+	return 0
+}
+
+func mathAbs(m *Machine, args []int16) (int16, error) {
+	if x := arg(args, 0); x < 0 {
+		return -x, nil
+	}
+	return arg(args, 0), nil
+}
+
+func mathMultiply(m *Machine, args []int16) (int16, error) {
+	return arg(args, 0) * arg(args, 1), nil
+}
+
+func mathDivide(m *Machine, args []int16) (int16, error) {
+	if arg(args, 1) == 0 {
+		return 0, ErrSysError{code: ErrorCodeDivideByZero}
+	}
+	return arg(args, 0) / arg(args, 1), nil
+}
+
+func mathMin(m *Machine, args []int16) (int16, error) {
+	return min(arg(args, 0), arg(args, 1)), nil
+}
+
+func mathMax(m *Machine, args []int16) (int16, error) {
+	return max(arg(args, 0), arg(args, 1)), nil
+}
+
+func mathSqrt(m *Machine, args []int16) (y int16, err error) {
+	x := arg(args, 0)
+	if x < 0 {
+		return 0, ErrSysError{code: ErrorCodeSqrtNegative}
+	}
+	for (int(y)+1)*(int(y)+1) <= int(x) {
+		y += 1
+	}
+	return
+}
+
+func memoryPeek(m *Machine, args []int16) (int16, error) {
+	return m.read(int(arg(args, 0)))
+}
+
+func memoryPoke(m *Machine, args []int16) (int16, error) {
+	return 0, m.write(int(arg(args, 0)), arg(args, 1))
+}
+
+// memoryAlloc finds the first free block large enough for size words plus
+// the header recording the block size at p-1.
+func memoryAlloc(m *Machine, args []int16) (int16, error) {
+	size := int(arg(args, 0))
+	if size <= 0 {
+		return 0, ErrSysError{code: ErrorCodeAllocNonPositive}
+	}
+
+	for i, free := range m.heap {
+		if free.size < size+1 {
+			continue
+		}
+		m.RAM[free.addr] = int16(size + 1)
+		if free.size == size+1 {
+			m.heap = slices.Delete(m.heap, i, i+1)
+		} else {
+			m.heap[i] = block{addr: free.addr + size + 1, size: free.size - size - 1}
+		}
+		return int16(free.addr + 1), nil
+	}
+	return 0, ErrSysError{code: ErrorCodeHeapOverflow}
+}
+
+func memoryDeAlloc(m *Machine, args []int16) (int16, error) {
+	addr := int(arg(args, 0)) - 1
+	if addr < HeapBase || addr >= HeapEnd {
+		return 0, ErrAddressInvalid{address: addr}
+	}
+
+	freed := block{addr: addr, size: int(m.RAM[addr])}
+	i, _ := slices.BinarySearchFunc(m.heap, freed, func(a, b block) int { return a.addr - b.addr })
+	m.heap = slices.Insert(m.heap, i, freed)
+
+	// Merge with the neighbouring blocks so the heap does not fragment.
+	if i+1 < len(m.heap) && m.heap[i].addr+m.heap[i].size == m.heap[i+1].addr {
+		m.heap[i].size += m.heap[i+1].size
+		m.heap = slices.Delete(m.heap, i+1, i+2)
+	}
+	if i > 0 && m.heap[i-1].addr+m.heap[i-1].size == m.heap[i].addr {
+		m.heap[i-1].size += m.heap[i].size
+		m.heap = slices.Delete(m.heap, i, i+1)
+	}
+	return 0, nil
+}
+
+func stringNew(m *Machine, args []int16) (str int16, err error) {
+	maxLength := arg(args, 0)
+	if maxLength < 0 {
+		return 0, ErrSysError{code: ErrorCodeStringNegative}
+	}
+	if str, err = memoryAlloc(m, []int16{maxLength + 2}); err != nil {
+		return
+	}
+	m.RAM[str] = maxLength
+	m.RAM[str+1] = 0
+	return
+}
+
+// NewString allocates a String object holding s.
+func (m *Machine) NewString(s string) (str int16, err error) {
+	if str, err = stringNew(m, []int16{int16(len(s))}); err != nil {
+		return
+	}
+	for _, char := range []byte(s) {
+		if _, err = stringAppendChar(m, []int16{str, int16(char)}); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// String returns the contents of the String object at str.
+func (m *Machine) String(str int16) (s string, err error) {
+	var length int16
+	if length, err = m.read(int(str) + 1); err != nil {
+		return
+	}
+
+	var b strings.Builder
+	for i := range int(length) {
+		var char int16
+		if char, err = m.read(int(str) + 2 + i); err != nil {
+			return
+		}
+		b.WriteByte(byte(char))
+	}
+	return b.String(), nil
+}
+
+func stringLength(m *Machine, args []int16) (int16, error) {
+	return m.read(int(arg(args, 0)) + 1)
+}
+
+func stringIndex(m *Machine, str, index int16) (addr int, err error) {
+	var length int16
+	if length, err = m.read(int(str) + 1); err != nil {
+		return
+	}
+	if index < 0 || index >= length {
+		return 0, ErrSysError{code: ErrorCodeStringIndex}
+	}
+	return int(str) + 2 + int(index), nil
+}
+
+func stringCharAt(m *Machine, args []int16) (char int16, err error) {
+	var addr int
+	if addr, err = stringIndex(m, arg(args, 0), arg(args, 1)); err != nil {
+		return
+	}
+	return m.read(addr)
+}
+
+func stringSetCharAt(m *Machine, args []int16) (_ int16, err error) {
+	var addr int
+	if addr, err = stringIndex(m, arg(args, 0), arg(args, 1)); err != nil {
+		return
+	}
+	return 0, m.write(addr, arg(args, 2))
+}
+
+func stringAppendChar(m *Machine, args []int16) (str int16, err error) {
+	str = arg(args, 0)
+
+	var maxLength, length int16
+	if maxLength, err = m.read(int(str)); err != nil {
+		return
+	}
+	if length, err = m.read(int(str) + 1); err != nil {
+		return
+	}
+	if length >= maxLength {
+		return 0, ErrSysError{code: ErrorCodeStringFull}
+	}
+	if err = m.write(int(str)+2+int(length), arg(args, 1)); err != nil {
+		return
+	}
+	m.RAM[int(str)+1] = length + 1
+	return
+}
+
+func stringEraseLastChar(m *Machine, args []int16) (_ int16, err error) {
+	str := int(arg(args, 0))
+
+	var length int16
+	if length, err = m.read(str + 1); err != nil {
+		return
+	}
+	if length == 0 {
+		return 0, ErrSysError{code: ErrorCodeStringEmpty}
+	}
+	m.RAM[str+1] = length - 1
+	return
+}
+
+func stringIntValue(m *Machine, args []int16) (value int16, err error) {
+	var s string
+	if s, err = m.String(arg(args, 0)); err != nil {
+		return
+	}
+
+	neg := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+	for _, char := range s {
+		if char < '0' || char > '9' {
+			break
+		}
+		value = value*10 + int16(char-'0')
+	}
+	if neg {
+		value = -value
+	}
+	return
+}
+
+func stringSetInt(m *Machine, args []int16) (_ int16, err error) {
+	str := arg(args, 0)
+	s := strconv.Itoa(int(arg(args, 1)))
+
+	var maxLength int16
+	if maxLength, err = m.read(int(str)); err != nil {
+		return
+	}
+	if int(maxLength) < len(s) {
+		return 0, ErrSysError{code: ErrorCodeStringInsufficient}
+	}
+	if err = m.write(int(str)+1, 0); err != nil {
+		return
+	}
+	for _, char := range []byte(s) {
+		if _, err = stringAppendChar(m, []int16{str, int16(char)}); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (m *Machine) print(s string) (err error) {
+	if m.Stdout != nil {
+		_, err = io.WriteString(m.Stdout, s)
+	}
+	return
+}
+
+func outputPrintChar(m *Machine, args []int16) (int16, error) {
+	switch char := arg(args, 0); char {
+	case KeyNewLine:
+		return 0, m.print("\n")
+	case KeyBackSpace:
+		return 0, m.print("\b")
+	default:
+		return 0, m.print(string(rune(char)))
+	}
+}
+
+func outputPrintString(m *Machine, args []int16) (_ int16, err error) {
+	var s string
+	if s, err = m.String(arg(args, 0)); err != nil {
+		return
+	}
+	return 0, m.print(s)
+}
+
+func outputPrintInt(m *Machine, args []int16) (int16, error) {
+	return 0, m.print(strconv.Itoa(int(arg(args, 0))))
+}
+
+func outputPrintln(m *Machine, args []int16) (int16, error) {
+	return 0, m.print("\n")
+}
+
+func outputBackSpace(m *Machine, args []int16) (int16, error) {
+	return 0, m.print("\b")
+}
+
+func screenClear(m *Machine, args []int16) (int16, error) {
+	clear(m.RAM[ScreenBase : ScreenBase+ScreenWidth*ScreenHeight/16])
+	return 0, nil
+}
+
+func screenSetColor(m *Machine, args []int16) (int16, error) {
+	m.color = arg(args, 0) != 0
+	return 0, nil
+}
+
+func (m *Machine) drawPixel(x, y int) {
+	if x < 0 || x >= ScreenWidth || y < 0 || y >= ScreenHeight {
+		return
+	}
+	addr := ScreenBase + y*ScreenWidth/16 + x/16
+	mask := int16(1) << (x % 16)
+	if m.color {
+		m.RAM[addr] |= mask
+	} else {
+		m.RAM[addr] &^= mask
+	}
+}
+
+func screenDrawPixel(m *Machine, args []int16) (int16, error) {
+	x, y := int(arg(args, 0)), int(arg(args, 1))
+	if x < 0 || x >= ScreenWidth || y < 0 || y >= ScreenHeight {
+		return 0, ErrSysError{code: ErrorCodeIllegalPixel}
+	}
+	m.drawPixel(x, y)
+	return 0, nil
+}
+
+func screenDrawLine(m *Machine, args []int16) (int16, error) {
+	x1, y1, x2, y2 := int(arg(args, 0)), int(arg(args, 1)), int(arg(args, 2)), int(arg(args, 3))
+	for _, v := range []int{x1, x2} {
+		if v < 0 || v >= ScreenWidth {
+			return 0, ErrSysError{code: ErrorCodeIllegalLine}
+		}
+	}
+	for _, v := range []int{y1, y2} {
+		if v < 0 || v >= ScreenHeight {
+			return 0, ErrSysError{code: ErrorCodeIllegalLine}
+		}
+	}
+
+	dx, dy := abs(x2-x1), -abs(y2-y1)
+	sx, sy := sign(x2-x1), sign(y2-y1)
+	e := dx + dy
+	for {
+		m.drawPixel(x1, y1)
+		if x1 == x2 && y1 == y2 {
+			break
+		}
+		if 2*e >= dy {
+			e += dy
+			x1 += sx
+		}
+		if 2*e <= dx {
+			e += dx
+			y1 += sy
+		}
+	}
+	return 0, nil
+}
+
+func screenDrawRectangle(m *Machine, args []int16) (int16, error) {
+	x1, y1, x2, y2 := int(arg(args, 0)), int(arg(args, 1)), int(arg(args, 2)), int(arg(args, 3))
+	if x1 < 0 || x1 > x2 || x2 >= ScreenWidth || y1 < 0 || y1 > y2 || y2 >= ScreenHeight {
+		return 0, ErrSysError{code: ErrorCodeIllegalRectangle}
+	}
+	for y := y1; y <= y2; y++ {
+		for x := x1; x <= x2; x++ {
+			m.drawPixel(x, y)
+		}
+	}
+	return 0, nil
+}
+
+func screenDrawCircle(m *Machine, args []int16) (int16, error) {
+	cx, cy, r := int(arg(args, 0)), int(arg(args, 1)), int(arg(args, 2))
+	if r < 0 || r > 181 || cx < 0 || cx >= ScreenWidth || cy < 0 || cy >= ScreenHeight {
+		return 0, ErrSysError{code: ErrorCodeIllegalCircle}
+	}
+	for dy := -r; dy <= r; dy++ {
+		for dx := -r; dx <= r; dx++ {
+			if dx*dx+dy*dy <= r*r {
+				m.drawPixel(cx+dx, cy+dy)
+			}
+		}
+	}
+	return 0, nil
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func sign(x int) int {
+	switch {
+	case x < 0:
+		return -1
+	case x > 0:
+		return 1
+	}
+	return 0
+}
+
+func keyboardKeyPressed(m *Machine, args []int16) (int16, error) {
+	return m.RAM[KeyboardAddress], nil
+}
+
+func (m *Machine) readByte() (char byte, err error) {
+	if m.Stdin == nil {
+		return 0, io.EOF
+	}
+	if m.stdin == nil {
+		m.stdin = bufio.NewReader(m.Stdin)
+	}
+	return m.stdin.ReadByte()
+}
+
+func keyboardReadChar(m *Machine, args []int16) (int16, error) {
+	char, err := m.readByte()
+	if err == io.EOF {
+		return KeyNewLine, nil
+	} else if char == '\n' {
+		return KeyNewLine, nil
+	}
+	return int16(char), err
+}
+
+func (m *Machine) readLine(message int16) (line string, err error) {
+	if _, err = outputPrintString(m, []int16{message}); err != nil {
+		return
+	}
+
+	var b strings.Builder
+	for {
+		var char byte
+		if char, err = m.readByte(); err == io.EOF {
+			return b.String(), nil
+		} else if err != nil {
+			return
+		}
+		if char == '\n' {
+			return strings.TrimRight(b.String(), "\r"), nil
+		}
+		b.WriteByte(char)
+	}
+}
+
+func keyboardReadLine(m *Machine, args []int16) (_ int16, err error) {
+	var line string
+	if line, err = m.readLine(arg(args, 0)); err != nil {
+		return
+	}
+	return m.NewString(line)
+}
+
+func keyboardReadInt(m *Machine, args []int16) (_ int16, err error) {
+	var line string
+	if line, err = m.readLine(arg(args, 0)); err != nil {
+		return
+	}
+
+	var str int16
+	if str, err = m.NewString(line); err != nil {
+		return
+	}
+	defer memoryDeAlloc(m, []int16{str})
+	return stringIntValue(m, []int16{str})
+}
+
+func sysHalt(m *Machine, args []int16) (int16, error) {
+	m.Halted = true
+	return 0, nil
+}
+
+func sysError(m *Machine, args []int16) (int16, error) {
+	m.Halted = true
+	return 0, ErrSysError{code: arg(args, 0)}
+}
@@ -0,0 +1,55 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vme
+
+const (
+	RAMSize = 32768
+
+	SP   = 0
+	LCL  = 1
+	ARG  = 2
+	THIS = 3
+	THAT = 4
+
+	StaticBase = 16
+
+	HeapBase = 2048
+	HeapEnd  = 16384
+
+	ScreenBase   = 16384
+	ScreenWidth  = 512
+	ScreenHeight = 256
+
+	KeyboardAddress = 24576
+)
+
+// Jack character set codes that differ from ASCII.
+const (
+	KeyNewLine     = 128
+	KeyBackSpace   = 129
+	KeyDoubleQuote = 34
+)
+
+// Error codes reported by the built-in OS through Sys.error.
+const (
+	ErrorCodeDivideByZero       = 3
+	ErrorCodeSqrtNegative       = 4
+	ErrorCodeAllocNonPositive   = 5
+	ErrorCodeHeapOverflow       = 6
+	ErrorCodeIllegalPixel       = 7
+	ErrorCodeIllegalLine        = 8
+	ErrorCodeIllegalRectangle   = 9
+	ErrorCodeIllegalCircle      = 12
+	ErrorCodeStringNegative     = 14
+	ErrorCodeStringIndex        = 15
+	ErrorCodeStringFull         = 17
+	ErrorCodeStringEmpty        = 18
+	ErrorCodeStringInsufficient = 19
+)
+
+const (
+	EntryFunction    = "Sys.init"
+	FallbackFunction = "Main.main"
+)
@@ -0,0 +1,67 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vme
+
+import (
+	"errors"
+	"hack/internal/vm"
+	"strconv"
+)
+
+var (
+	ErrStackUnderflow = errors.New("stack underflow")
+)
+
+type (
+	ErrLabelUndefined struct {
+		label string
+	}
+
+	ErrAddressInvalid struct {
+		address int
+	}
+
+	ErrSysError struct {
+		code int16
+	}
+
+	ErrUnhandledCommand struct {
+		cmd vm.Command
+	}
+
+	ErrUnhandledSegment struct {
+		cmd vm.Command
+		seg vm.Segment
+	}
+
+	ErrIndexOutOfRange struct {
+		seg   vm.Segment
+		index int16
+	}
+)
+
+func (err ErrLabelUndefined) Error() string {
+	return "undefined label: " + err.label
+}
+
+func (err ErrAddressInvalid) Error() string {
+	return "invalid address: " + strconv.Itoa(err.address)
+}
+
+func (err ErrSysError) Error() string {
+	return "Sys.error: " + strconv.Itoa(int(err.code))
+}
+
+func (err ErrUnhandledCommand) Error() string {
+	return "unhandled command: " + err.cmd.String()
+}
+
+func (err ErrUnhandledSegment) Error() string {
+	return "unhandled segment for " + err.cmd.String() + ": " + err.seg.String()
+}
+
+func (err ErrIndexOutOfRange) Error() string {
+	return "index out of range for " + err.seg.String() + ": " + strconv.Itoa(int(err.index))
+}
@@ -0,0 +1,414 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vme
+
+import (
+	"bufio"
+	"hack/internal/vm"
+	"io"
+	"strconv"
+)
+
+type (
+	// Machine executes VM programs directly, modelling the stack, the memory
+	// segments and the heap in the Hack RAM. Functions called but not loaded
+	// are looked up in Builtins.
+	Machine struct {
+		RAM [RAMSize]int16
+
+		// PC is the index of the next statement to execute.
+		PC     int
+		Halted bool
+
+		// Steps counts the statements executed.
+		Steps int
+
+		Stdout io.Writer
+		Stdin  io.Reader
+
+		code       []instruction
+		functions  map[string]int
+		statics    map[string]int16
+		nextStatic int16
+		heap       []block
+		stdin      *bufio.Reader
+		entry      string
+		color      bool
+	}
+
+	instruction struct {
+		vm.Statement
+
+		File  string
+		Scope string
+
+		// Target is the statement jumped to by goto, if-goto and call, or -1
+		// for a call to a builtin.
+		Target int
+		Static int16
+	}
+
+	// Builtin implements an OS function natively. Void functions return 0.
+	Builtin func(m *Machine, args []int16) (int16, error)
+)
+
+func New() *Machine {
+	return &Machine{
+		functions:  map[string]int{},
+		statics:    map[string]int16{},
+		nextStatic: StaticBase,
+		color:      true,
+		heap:       []block{{addr: HeapBase, size: HeapEnd - HeapBase}},
+	}
+}
+
+// Load appends prog, read from the file named name, to the loaded code.
+// Static variables are allocated in order of first appearance, like the
+// assembler allocates the variables of translated code.
+func (m *Machine) Load(name string, prog vm.Program) {
+	var function string
+	for _, stmt := range prog {
+		if stmt.Command == vm.CommandFunction {
+			function = stmt.Function
+			m.functions[function] = len(m.code)
+		}
+
+		instr := instruction{Statement: stmt, File: name, Scope: function}
+		if stmt.Segment == vm.SegmentStatic && (stmt.Command == vm.CommandPush || stmt.Command == vm.CommandPop) {
+			key := name + "." + strconv.Itoa(int(stmt.Index))
+			if _, ok := m.statics[key]; !ok {
+				m.statics[key] = m.nextStatic
+				m.nextStatic += 1
+			}
+			instr.Static = m.statics[key]
+		}
+		m.code = append(m.code, instr)
+	}
+}
+
+// Link resolves the jump and call targets of the loaded code and sets PC to
+// the entry point: Sys.init if loaded, then Main.main, then the first
+// statement.
+func (m *Machine) Link() (err error) {
+	// Builtins count as defined, as if the OS were loaded.
+	prog := make(vm.Program, 0, len(m.code)+len(Builtins))
+	for _, instr := range m.code {
+		prog = append(prog, instr.Statement)
+	}
+	for function := range Builtins {
+		prog = append(prog, vm.Statement{Command: vm.CommandFunction, Function: function})
+	}
+	if err = vm.Link(nil, prog); err != nil {
+		return
+	}
+
+	labels := map[string]int{}
+	for idx, instr := range m.code {
+		if instr.Command == vm.CommandLabel {
+			labels[scope(instr.Scope, instr.Label)] = idx
+		}
+	}
+
+	for idx := range m.code {
+		instr := &m.code[idx]
+		switch instr.Command {
+		case vm.CommandGoto, vm.CommandIfGoto:
+			var ok bool
+			if instr.Target, ok = labels[scope(instr.Scope, instr.Label)]; !ok {
+				return ErrLabelUndefined{label: instr.Label}
+			}
+		case vm.CommandCall:
+			if target, ok := m.functions[instr.Function]; ok {
+				instr.Target = target
+			} else {
+				instr.Target = -1
+			}
+		}
+	}
+
+	m.PC = 0
+	m.entry = ""
+	if target, ok := m.functions[EntryFunction]; ok {
+		m.PC = target
+	} else if _, ok := m.functions[FallbackFunction]; ok {
+		m.entry = FallbackFunction
+	}
+	return
+}
+
+// CurrentFunction returns the name of the function being executed.
+func (m *Machine) CurrentFunction() string {
+	if m.PC < 0 || m.PC >= len(m.code) {
+		return ""
+	}
+	return m.code[m.PC].Scope
+}
+
+// Run executes up to n statements, stopping early if the machine halts.
+func (m *Machine) Run(n int) (err error) {
+	for range n {
+		if m.Halted {
+			return
+		}
+		if err = m.Step(); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// Step executes the statement at PC.
+func (m *Machine) Step() (err error) {
+	if m.entry != "" {
+		entry := m.entry
+		m.entry = ""
+		if m.RAM[SP] == 0 {
+			m.RAM[SP] = 256
+		}
+		return m.call(m.functions[entry], 0, len(m.code))
+	}
+
+	// Labels only mark positions, so they are skipped instead of taking a
+	// step of their own.
+	for m.PC >= 0 && m.PC < len(m.code) && m.code[m.PC].Command == vm.CommandLabel {
+		m.PC += 1
+	}
+
+	if m.Halted || m.PC < 0 || m.PC >= len(m.code) {
+		m.Halted = true
+		return
+	}
+
+	instr := m.code[m.PC]
+	m.Steps += 1
+	m.PC += 1
+
+	switch instr.Command {
+	case vm.CommandPush:
+		var value int16
+		if value, err = m.load(instr); err != nil {
+			return
+		}
+		err = m.push(value)
+	case vm.CommandPop:
+		var value int16
+		if value, err = m.pop(); err != nil {
+			return
+		}
+		err = m.store(instr, value)
+	case vm.CommandAdd, vm.CommandSub, vm.CommandAnd, vm.CommandOr, vm.CommandEq, vm.CommandGt, vm.CommandLt:
+		var x, y int16
+		if y, err = m.pop(); err != nil {
+			return
+		}
+		if x, err = m.pop(); err != nil {
+			return
+		}
+		err = m.push(binary(instr.Command, x, y))
+	case vm.CommandNeg:
+		var x int16
+		if x, err = m.pop(); err != nil {
+			return
+		}
+		err = m.push(-x)
+	case vm.CommandNot:
+		var x int16
+		if x, err = m.pop(); err != nil {
+			return
+		}
+		err = m.push(^x)
+	case vm.CommandLabel:
+	case vm.CommandGoto:
+		m.PC = instr.Target
+	case vm.CommandIfGoto:
+		var cond int16
+		if cond, err = m.pop(); err != nil {
+			return
+		}
+		if cond != 0 {
+			m.PC = instr.Target
+		}
+	case vm.CommandFunction:
+		for range instr.Count {
+			if err = m.push(0); err != nil {
+				return
+			}
+		}
+	case vm.CommandCall:
+		if instr.Target < 0 {
+			err = m.callBuiltin(instr.Function, int(instr.Count))
+		} else {
+			err = m.call(instr.Target, instr.Count, m.PC)
+		}
+	case vm.CommandReturn:
+		err = m.ret()
+	default:
+		err = ErrUnhandledCommand{cmd: instr.Command}
+	}
+
+	return
+}
+
+func (m *Machine) call(target int, args int16, ret int) (err error) {
+	for _, value := range []int16{int16(ret), m.RAM[LCL], m.RAM[ARG], m.RAM[THIS], m.RAM[THAT]} {
+		if err = m.push(value); err != nil {
+			return
+		}
+	}
+	m.RAM[ARG] = m.RAM[SP] - vm.FrameSize - args
+	m.RAM[LCL] = m.RAM[SP]
+	m.PC = target
+	return
+}
+
+func (m *Machine) callBuiltin(function string, n int) (err error) {
+	args := make([]int16, n)
+	for i := n - 1; i >= 0; i-- {
+		if args[i], err = m.pop(); err != nil {
+			return
+		}
+	}
+
+	var result int16
+	if result, err = Builtins[function](m, args); err != nil {
+		return
+	}
+	return m.push(result)
+}
+
+func (m *Machine) ret() (err error) {
+	frame := int(m.RAM[LCL])
+
+	var value int16
+	if value, err = m.pop(); err != nil {
+		return
+	}
+
+	var saved [vm.FrameSize]int16
+	for i := range saved {
+		if saved[i], err = m.read(frame - vm.FrameSize + i); err != nil {
+			return
+		}
+	}
+
+	if err = m.write(int(m.RAM[ARG]), value); err != nil {
+		return
+	}
+	m.RAM[SP] = m.RAM[ARG] + 1
+	m.RAM[LCL], m.RAM[ARG], m.RAM[THIS], m.RAM[THAT] = saved[1], saved[2], saved[3], saved[4]
+	m.PC = int(saved[0])
+	return
+}
+
+func (m *Machine) address(instr instruction) (addr int, err error) {
+	index := int(instr.Index)
+	switch instr.Segment {
+	case vm.SegmentArgument:
+		addr = int(m.RAM[ARG]) + index
+	case vm.SegmentLocal:
+		addr = int(m.RAM[LCL]) + index
+	case vm.SegmentThis:
+		addr = int(m.RAM[THIS]) + index
+	case vm.SegmentThat:
+		addr = int(m.RAM[THAT]) + index
+	case vm.SegmentPointer:
+		if index >= vm.PointerSize {
+			return 0, ErrIndexOutOfRange{seg: instr.Segment, index: instr.Index}
+		}
+		addr = vm.PointerBase + index
+	case vm.SegmentTemp:
+		if index >= vm.TempSize {
+			return 0, ErrIndexOutOfRange{seg: instr.Segment, index: instr.Index}
+		}
+		addr = vm.TempBase + index
+	case vm.SegmentStatic:
+		addr = int(instr.Static)
+	default:
+		err = ErrUnhandledSegment{cmd: instr.Command, seg: instr.Segment}
+	}
+	return
+}
+
+func (m *Machine) load(instr instruction) (value int16, err error) {
+	if instr.Segment == vm.SegmentConstant {
+		return instr.Index, nil
+	}
+
+	var addr int
+	if addr, err = m.address(instr); err != nil {
+		return
+	}
+	return m.read(addr)
+}
+
+func (m *Machine) store(instr instruction, value int16) (err error) {
+	var addr int
+	if addr, err = m.address(instr); err != nil {
+		return
+	}
+	return m.write(addr, value)
+}
+
+func (m *Machine) read(addr int) (value int16, err error) {
+	if addr < 0 || addr >= RAMSize {
+		return 0, ErrAddressInvalid{address: addr}
+	}
+	return m.RAM[addr], nil
+}
+
+func (m *Machine) write(addr int, value int16) (err error) {
+	if addr < 0 || addr >= RAMSize {
+		return ErrAddressInvalid{address: addr}
+	}
+	m.RAM[addr] = value
+	return
+}
+
+func (m *Machine) push(value int16) (err error) {
+	if err = m.write(int(m.RAM[SP]), value); err != nil {
+		return
+	}
+	m.RAM[SP] += 1
+	return
+}
+
+func (m *Machine) pop() (value int16, err error) {
+	if m.RAM[SP] <= 0 {
+		return 0, ErrStackUnderflow
+	}
+	m.RAM[SP] -= 1
+	return m.read(int(m.RAM[SP]))
+}
+
+func binary(cmd vm.Command, x, y int16) int16 {
+	switch cmd {
+	case vm.CommandAdd:
+		return x + y
+	case vm.CommandSub:
+		return x - y
+	case vm.CommandAnd:
+		return x & y
+	case vm.CommandOr:
+		return x | y
+	case vm.CommandEq:
+		return boolean(x == y)
+	case vm.CommandGt:
+		return boolean(x > y)
+	case vm.CommandLt:
+		return boolean(x < y)
+	}
+	return 0
+}
+
+func boolean(b bool) int16 {
+	if b {
+		return -1
+	}
+	return 0
+}
+
+func scope(function, label string) string {
+	return function + "$" + label
+}
@@ -0,0 +1,159 @@
+// Copyright 2024 xeraph. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vme
+
+import (
+	"bytes"
+	"hack/internal/vm"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func load(t *testing.T, files map[string]string) *Machine {
+	m := New()
+	for name, src := range files {
+		prog, err := vm.ParseString(src)
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+		m.Load(name, prog)
+	}
+	if !assert.Nil(t, m.Link()) {
+		t.FailNow()
+	}
+	return m
+}
+
+func TestMachineArithmetic(t *testing.T) {
+	m := load(t, map[string]string{"Main": `
+push constant 7
+push constant 8
+add
+push constant 3
+gt
+push constant 5
+neg
+not
+`})
+	m.RAM[SP] = 256
+
+	assert.Nil(t, m.Run(100))
+	assert.True(t, m.Halted)
+	assert.Equal(t, int16(258), m.RAM[SP])
+	assert.Equal(t, int16(-1), m.RAM[256])
+	assert.Equal(t, int16(4), m.RAM[257])
+}
+
+func TestMachineCall(t *testing.T) {
+	m := load(t, map[string]string{"Main": `
+function Main.main 0
+push constant 3
+call Main.double 1
+pop static 0
+label END
+goto END
+
+function Main.double 1
+push argument 0
+push argument 0
+add
+pop local 0
+push local 0
+return
+`})
+
+	assert.Nil(t, m.Run(50))
+	assert.Equal(t, int16(6), m.RAM[StaticBase])
+	assert.Equal(t, "Main.main", m.CurrentFunction())
+}
+
+func TestMachineBuiltins(t *testing.T) {
+	m := load(t, map[string]string{"Main": `
+function Main.main 1
+push constant 2
+call String.new 1
+push constant 72
+call String.appendChar 2
+push constant 105
+call String.appendChar 2
+pop local 0
+push local 0
+call Output.printString 1
+pop temp 0
+push constant 6
+push constant 7
+call Math.multiply 2
+call Output.printInt 1
+pop temp 0
+push local 0
+call String.dispose 1
+pop temp 0
+push constant 0
+return
+`})
+	var out bytes.Buffer
+	m.Stdout = &out
+
+	assert.Nil(t, m.Run(100))
+	assert.True(t, m.Halted)
+	assert.Equal(t, "Hi42", out.String())
+	assert.Equal(t, []block{{addr: HeapBase, size: HeapEnd - HeapBase}}, m.heap)
+}
+
+func TestStringSetIntAddress(t *testing.T) {
+	m := New()
+	m.RAM[RAMSize-1] = 5
+	_, err := stringSetInt(m, []int16{RAMSize - 1, 42})
+	assert.Equal(t, ErrAddressInvalid{address: RAMSize}, err)
+}
+
+func TestMachineError(t *testing.T) {
+	t.Run("undefined function", func(t *testing.T) {
+		prog, _ := vm.ParseString("call Foo.bar 0")
+		m := New()
+		m.Load("Main", prog)
+		err := m.Link()
+		assert.IsType(t, vm.ErrFunctionUndefined{}, err)
+		assert.EqualError(t, err, "undefined function: Foo.bar")
+	})
+
+	t.Run("sys error", func(t *testing.T) {
+		m := load(t, map[string]string{"Main": `
+push constant 1
+push constant 0
+call Math.divide 2
+`})
+		m.RAM[SP] = 256
+		assert.Equal(t, ErrSysError{code: ErrorCodeDivideByZero}, m.Run(10))
+	})
+
+	t.Run("index out of range", func(t *testing.T) {
+		m := load(t, map[string]string{"Main": "push temp 8"})
+		m.RAM[SP] = 256
+		err := m.Run(10)
+		assert.Equal(t, ErrIndexOutOfRange{seg: vm.SegmentTemp, index: 8}, err)
+		assert.EqualError(t, err, "index out of range for temp: 8")
+	})
+
+	t.Run("unhandled segment", func(t *testing.T) {
+		m := load(t, map[string]string{"Main": "push constant 1\npop constant 0"})
+		m.RAM[SP] = 256
+		err := m.Run(10)
+		assert.Equal(t, ErrUnhandledSegment{cmd: vm.CommandPop, seg: vm.SegmentConstant}, err)
+		assert.EqualError(t, err, "unhandled segment for pop: constant")
+	})
+
+	t.Run("unhandled command", func(t *testing.T) {
+		m := load(t, map[string]string{"Main": "push constant 1"})
+		m.code[0].Command = vm.Command(-1)
+		assert.Equal(t, ErrUnhandledCommand{cmd: vm.Command(-1)}, m.Run(10))
+	})
+
+	t.Run("stack underflow", func(t *testing.T) {
+		m := load(t, map[string]string{"Main": "add"})
+		assert.Equal(t, ErrStackUnderflow, m.Run(10))
+	})
+}